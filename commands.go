@@ -0,0 +1,845 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/likecoin/iscn-poc/node"
+	"github.com/likecoin/iscn-poc/record"
+	"github.com/tidwall/pretty"
+
+	ipld "github.com/ipfs/go-ipld-format"
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// demoRegistrant is the registrant of the IDs allocated by the demo.
+const demoRegistrant = "demo"
+
+// fetchTimeout bounds the fetch of a linked block, which may not be stored
+// locally and is then looked up on the network.
+const fetchTimeout = 30 * time.Second
+
+// timeoutGetter gets each block with fetchTimeout, so a block missing from
+// the network fails instead of blocking forever.
+type timeoutGetter struct {
+	ipld.NodeGetter
+}
+
+func (g timeoutGetter) Get(ctx context.Context, c cid.Cid) (ipld.Node, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	return g.NodeGetter.Get(ctx, c)
+}
+
+func (g timeoutGetter) GetMany(ctx context.Context, cids []cid.Cid) <-chan *ipld.NodeOption {
+	out := make(chan *ipld.NodeOption, len(cids))
+	for _, c := range cids {
+		nd, err := g.Get(ctx, c)
+		out <- &ipld.NodeOption{Node: nd, Err: err}
+	}
+	close(out)
+	return out
+}
+
+// codecName returns the name of an ISCN codec, or the codec in hex.
+func codecName(codec uint64) string {
+	if name, ok := record.CodecName(codec); ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", codec)
+}
+
+// formatCid formats the CID in base58btc as the demos do.
+func formatCid(c cid.Cid) string {
+	str, err := c.StringOfBase('z')
+	if err != nil {
+		return c.String()
+	}
+	return str
+}
+
+// stopNode stops the node and reports the failure of the shutdown in err if
+// there is no other error.
+func stopNode(n *node.Node, err *error) {
+	if stopErr := n.Stop(); stopErr != nil && *err == nil {
+		*err = stopErr
+	}
+}
+
+func runInit(ctx context.Context, settings *node.Settings, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: init")
+	}
+
+	log.Println("Initializing repos ...")
+	if err := node.Init(settings); err != nil {
+		return err
+	}
+	log.Println("Repos are initialized")
+
+	return nil
+}
+
+func runDaemon(ctx context.Context, settings *node.Settings, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: daemon")
+	}
+
+	log.Println("Setting up IPFS node ...")
+	n := node.New(settings)
+	if err := n.Start(ctx); err != nil {
+		n.Stop()
+		return err
+	}
+	log.Println("IPFS node is created")
+
+	waitForSignal()
+
+	return n.Stop()
+}
+
+func runAdd(
+	ctx context.Context,
+	settings *node.Settings,
+	args []string,
+) (err error) {
+	flags := flag.NewFlagSet("add", flag.ContinueOnError)
+	registrant := flags.String(
+		"registrant",
+		"",
+		"registrant of the ID of a kernel, required for kernels",
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("usage: add [-registrant <registrant>] <codec> <file.json>")
+	}
+	name, path := flags.Arg(0), flags.Arg(1)
+
+	codec, ok := record.CodecByName(name)
+	if !ok {
+		return fmt.Errorf("unknown codec %q", name)
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	b, err := record.FromJSON(codec, record.SchemaVersion, raw)
+	if err != nil {
+		return fmt.Errorf("cannot create %s block: %s", name, err)
+	}
+
+	var kernel *record.Kernel
+	if codec == iscn.CodecISCN {
+		if len(*registrant) == 0 {
+			return errors.New("a kernel needs -registrant to check its ID")
+		}
+
+		kernel = &record.Kernel{}
+		if err := kernel.FromObject(b); err != nil {
+			return fmt.Errorf("cannot read kernel: %s", err)
+		}
+		if keys := kernel.Custom.Unregistered(); len(keys) > 0 {
+			log.Printf("Unregistered custom properties: %s", strings.Join(keys, ", "))
+		}
+	}
+
+	log.Println("Setting up IPFS node ...")
+	n := node.New(settings)
+	defer stopNode(n, &err)
+	if err := n.Start(ctx); err != nil {
+		return err
+	}
+
+	if err := record.Validate(ctx, n.DAG(), b); err != nil {
+		return fmt.Errorf("invalid %s block: %s", name, err)
+	}
+
+	// The ID is reserved once the kernel is pinned, so a failed pin does not
+	// leave the ID reserved
+	if kernel != nil {
+		owner, err := n.IDOwner(kernel.ID)
+		if err != nil {
+			return fmt.Errorf("cannot register kernel ID: %s", err)
+		}
+		if len(owner) > 0 && owner != *registrant {
+			return fmt.Errorf(
+				"cannot register kernel ID: %s of %q: %w",
+				node.FormatID(kernel.ID),
+				owner,
+				node.ErrDuplicateID,
+			)
+		}
+	}
+
+	if err := n.DAG().Pinning().Add(ctx, b); err != nil {
+		return fmt.Errorf("cannot pin IPLD: %s", err)
+	}
+
+	if kernel != nil {
+		if err := n.ReserveID(*registrant, kernel.ID); err != nil {
+			return fmt.Errorf("cannot register kernel ID: %s", err)
+		}
+	}
+
+	if _, err := n.Commit(); err != nil {
+		return fmt.Errorf("cannot commit: %s", err)
+	}
+
+	c, err := b.Cid().StringOfBase('z')
+	if err != nil {
+		return fmt.Errorf("cannot retrieve CID from block: %s", err)
+	}
+	fmt.Println(c)
+
+	return nil
+}
+
+func runGet(
+	ctx context.Context,
+	settings *node.Settings,
+	args []string,
+) (err error) {
+	flags := flag.NewFlagSet("get", flag.ContinueOnError)
+	version := flags.Int64(
+		"version",
+		0,
+		"read the block as of this version (block height) of the Cosmos store",
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: get [-version <version>] <cid or path>")
+	}
+
+	c, path, err := record.ParsePath(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	log.Println("Setting up IPFS node ...")
+	n := node.New(settings)
+	defer stopNode(n, &err)
+	if err := n.Start(ctx); err != nil {
+		return err
+	}
+
+	var dag ipld.NodeGetter = n.DAG()
+	if *version > 0 {
+		snapshot, err := n.Snapshot(*version)
+		if err != nil {
+			return err
+		}
+		dag = snapshot
+	}
+
+	if len(path) > 0 {
+		value, err := record.Get(ctx, dag, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		return printValue(value)
+	}
+
+	ret, err := dag.Get(ctx, c)
+	if err != nil {
+		return fmt.Errorf("cannot fetch IPLD: %s", err)
+	}
+
+	obj, err := iscn.Decode(ret.RawData(), c)
+	if err != nil {
+		return fmt.Errorf("cannot decode IPLD raw data: %s", err)
+	}
+
+	raw, err := obj.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("cannot marshal JSON: %s", err)
+	}
+	fmt.Print(string(pretty.Pretty(raw)))
+
+	return nil
+}
+
+func runDiff(
+	ctx context.Context,
+	settings *node.Settings,
+	args []string,
+) (err error) {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the changes as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("usage: diff [-json] <cid> <cid>")
+	}
+
+	cids := make([]cid.Cid, 2)
+	for i := range cids {
+		if cids[i], err = cid.Decode(flags.Arg(i)); err != nil {
+			return fmt.Errorf("cannot parse CID %q: %s", flags.Arg(i), err)
+		}
+	}
+
+	log.Println("Setting up IPFS node ...")
+	n := node.New(settings)
+	defer stopNode(n, &err)
+	if err := n.Start(ctx); err != nil {
+		return err
+	}
+
+	objs := make([]iscn.IscnObject, 2)
+	for i, c := range cids {
+		ret, err := n.DAG().Get(ctx, c)
+		if err != nil {
+			return fmt.Errorf("cannot fetch IPLD: %s", err)
+		}
+		if objs[i], err = iscn.Decode(ret.RawData(), c); err != nil {
+			return fmt.Errorf("cannot decode IPLD raw data: %s", err)
+		}
+	}
+
+	changes, err := record.Diff(objs[0], objs[1])
+	if err != nil {
+		return err
+	}
+
+	if !*asJSON {
+		if len(changes) > 0 {
+			fmt.Println(changes)
+		}
+		return nil
+	}
+
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("cannot marshal JSON: %s", err)
+	}
+	fmt.Print(string(pretty.Pretty(raw)))
+
+	return nil
+}
+
+func runHistory(
+	ctx context.Context,
+	settings *node.Settings,
+	args []string,
+) (err error) {
+	if len(args) != 1 {
+		return errors.New("usage: history <content cid>")
+	}
+
+	c, err := cid.Decode(args[0])
+	if err != nil {
+		return fmt.Errorf("cannot parse CID %q: %s", args[0], err)
+	}
+
+	log.Println("Setting up IPFS node ...")
+	n := node.New(settings)
+	defer stopNode(n, &err)
+	if err := n.Start(ctx); err != nil {
+		return err
+	}
+
+	known, err := n.Blocks()
+	if err != nil {
+		return err
+	}
+
+	history, err := record.ContentHistory(ctx, n.DAG(), c, known)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range history.Versions {
+		fmt.Printf(
+			"%s\t%d\t%s\t%s\n",
+			formatCid(v.Cid),
+			v.Content.Version,
+			v.Content.Edition,
+			v.Content.Fingerprint,
+		)
+	}
+	for _, problem := range history.Problems {
+		log.Printf("Inconsistent history: %s", problem)
+	}
+
+	return nil
+}
+
+func runResolve(
+	ctx context.Context,
+	settings *node.Settings,
+	args []string,
+) (err error) {
+	flags := flag.NewFlagSet("resolve", flag.ContinueOnError)
+	depth := flags.Int(
+		"depth",
+		-1,
+		"number of links to inline from the kernel, negative for no limit",
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: resolve [-depth <depth>] <kernel cid>")
+	}
+
+	c, err := cid.Decode(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("cannot parse CID %q: %s", flags.Arg(0), err)
+	}
+
+	log.Println("Setting up IPFS node ...")
+	n := node.New(settings)
+	defer stopNode(n, &err)
+	if err := n.Start(ctx); err != nil {
+		return err
+	}
+
+	resolved, err := record.ResolveKernel(ctx, timeoutGetter{n.DAG()}, c, *depth)
+	if err != nil {
+		return err
+	}
+	for _, problem := range resolved.Problems {
+		log.Printf("Cannot resolve %s", problem)
+	}
+
+	raw, err := resolved.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("cannot marshal JSON: %s", err)
+	}
+	fmt.Print(string(pretty.Pretty(raw)))
+
+	return nil
+}
+
+func runExport(
+	ctx context.Context,
+	settings *node.Settings,
+	args []string,
+) (err error) {
+	if len(args) != 1 {
+		return errors.New("usage: export <kernel cid> > <file.car>")
+	}
+
+	c, err := cid.Decode(args[0])
+	if err != nil {
+		return fmt.Errorf("cannot parse CID %q: %s", args[0], err)
+	}
+
+	log.Println("Setting up IPFS node ...")
+	n := node.New(settings)
+	defer stopNode(n, &err)
+	if err := n.Start(ctx); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	exported, err := record.ExportCAR(ctx, n.DAG(), c, w)
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("cannot write CAR: %s", err)
+	}
+	log.Printf("Exported %d blocks", len(exported))
+
+	return nil
+}
+
+// carGetter gets the blocks of a CAR before the blocks of the node, so the
+// blocks of a CAR can be validated before they are added.
+type carGetter struct {
+	blocks map[cid.Cid]ipld.Node
+	ipld.NodeGetter
+}
+
+func (g carGetter) Get(ctx context.Context, c cid.Cid) (ipld.Node, error) {
+	if nd, ok := g.blocks[c]; ok {
+		return nd, nil
+	}
+	return g.NodeGetter.Get(ctx, c)
+}
+
+func (g carGetter) GetMany(ctx context.Context, cids []cid.Cid) <-chan *ipld.NodeOption {
+	out := make(chan *ipld.NodeOption, len(cids))
+	for _, c := range cids {
+		nd, err := g.Get(ctx, c)
+		out <- &ipld.NodeOption{Node: nd, Err: err}
+	}
+	close(out)
+	return out
+}
+
+func runImport(
+	ctx context.Context,
+	settings *node.Settings,
+	args []string,
+) (err error) {
+	if len(args) != 1 {
+		return errors.New("usage: import <file.car>")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	roots, blks, err := record.ReadCAR(f)
+	if err != nil {
+		return err
+	}
+
+	nodes := []ipld.Node{}
+	objs := []iscn.IscnObject{}
+	byCid := map[cid.Cid]ipld.Node{}
+	for _, b := range blks {
+		var nd ipld.Node
+		if record.IsISCN(b.Cid()) {
+			nd, err = iscn.Decode(b.RawData(), b.Cid())
+		} else {
+			nd, err = ipld.Decode(b)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot decode block %s: %s", b.Cid(), err)
+		}
+		if obj, ok := nd.(iscn.IscnObject); ok {
+			objs = append(objs, obj)
+		}
+		nodes = append(nodes, nd)
+		byCid[b.Cid()] = nd
+	}
+
+	log.Println("Setting up IPFS node ...")
+	n := node.New(settings)
+	defer stopNode(n, &err)
+	if err := n.Start(ctx); err != nil {
+		return err
+	}
+
+	getter := carGetter{blocks: byCid, NodeGetter: n.DAG()}
+	for _, obj := range objs {
+		if err := record.Validate(ctx, getter, obj); err != nil {
+			return fmt.Errorf("invalid block %s: %s", obj.Cid(), err)
+		}
+	}
+
+	if err := n.DAG().AddMany(ctx, nodes); err != nil {
+		return fmt.Errorf("cannot add IPLD: %s", err)
+	}
+	for _, root := range roots {
+		nd, ok := byCid[root]
+		if !ok {
+			return fmt.Errorf("root %s is not in the CAR", root)
+		}
+		if err := n.DAG().Pinning().Add(ctx, nd); err != nil {
+			return fmt.Errorf("cannot pin IPLD: %s", err)
+		}
+	}
+
+	if _, err := n.Commit(); err != nil {
+		return fmt.Errorf("cannot commit: %s", err)
+	}
+
+	log.Printf("Imported %d blocks", len(nodes))
+	for _, root := range roots {
+		fmt.Println(formatCid(root))
+	}
+
+	return nil
+}
+
+// printValue prints a value queried by a path, the strings and the CIDs are
+// printed as they are and the others as JSON.
+func printValue(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		fmt.Println(v)
+	case cid.Cid:
+		fmt.Println(formatCid(v))
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("cannot marshal JSON: %s", err)
+		}
+		fmt.Print(string(pretty.Pretty(raw)))
+	}
+	return nil
+}
+
+func runStatus(
+	ctx context.Context,
+	settings *node.Settings,
+	args []string,
+) (err error) {
+	if len(args) != 0 {
+		return errors.New("usage: status")
+	}
+
+	n := node.New(settings)
+	defer stopNode(n, &err)
+	if err := n.Start(ctx); err != nil {
+		return err
+	}
+
+	last, err := n.LastCommit()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Version: %d\n", last.Version)
+	fmt.Printf("App hash: %X\n", last.Hash)
+
+	return nil
+}
+
+func runMigrate(
+	ctx context.Context,
+	settings *node.Settings,
+	args []string,
+) (err error) {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	to := flags.Uint64(
+		"to",
+		record.SchemaVersion,
+		"schema version to migrate the blocks to",
+	)
+	rewrite := flags.Bool(
+		"rewrite",
+		false,
+		"rewrite the blocks and print the mapping of the old and the new CIDs",
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("usage: migrate [-to <version>] [-rewrite]")
+	}
+
+	log.Println("Setting up IPFS node ...")
+	n := node.New(settings)
+	defer stopNode(n, &err)
+	if err := n.Start(ctx); err != nil {
+		return err
+	}
+
+	cids, err := n.Blocks()
+	if err != nil {
+		return err
+	}
+	outdated := 0
+	for _, c := range cids {
+		nd, err := n.DAG().Get(ctx, c)
+		if err != nil {
+			return fmt.Errorf("cannot fetch block %s: %s", c, err)
+		}
+		obj, err := iscn.Decode(nd.RawData(), c)
+		if err != nil {
+			return fmt.Errorf("cannot decode block %s: %s", c, err)
+		}
+
+		if obj.GetVersion() != *to {
+			outdated++
+			if !*rewrite {
+				fmt.Printf(
+					"%s\t%s\t%d\n",
+					formatCid(c),
+					codecName(c.Type()),
+					obj.GetVersion(),
+				)
+			}
+		}
+	}
+	log.Printf(
+		"%d of %d blocks are not of schema version %d",
+		outdated,
+		len(cids),
+		*to,
+	)
+
+	if !*rewrite {
+		return nil
+	}
+
+	m := record.NewMigrator(timeoutGetter{n.DAG()}, *to)
+	for _, c := range cids {
+		if _, err := m.Migrate(ctx, c); err != nil {
+			return err
+		}
+	}
+
+	for _, obj := range m.Objects() {
+		if err := n.DAG().Pinning().Add(ctx, obj); err != nil {
+			return fmt.Errorf("cannot pin block %s: %s", obj.Cid(), err)
+		}
+	}
+	if _, err := n.Commit(); err != nil {
+		return fmt.Errorf("cannot commit: %s", err)
+	}
+
+	for old, migrated := range m.Mapping() {
+		fmt.Printf("%s\t%s\n", formatCid(old), formatCid(migrated))
+	}
+	log.Printf("%d blocks are rewritten", len(m.Objects()))
+
+	return nil
+}
+
+func runSchema(
+	ctx context.Context,
+	settings *node.Settings,
+	args []string,
+) error {
+	flags := flag.NewFlagSet("schema", flag.ContinueOnError)
+	version := flags.Uint64(
+		"version",
+		record.SchemaVersion,
+		"schema version of the blocks",
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: schema [-version <version>] <codec>")
+	}
+
+	codec, ok := record.CodecByName(flags.Arg(0))
+	if !ok {
+		return fmt.Errorf("unknown codec %q", flags.Arg(0))
+	}
+
+	schema, err := record.MarshalJSONSchema(codec, *version)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(schema))
+
+	return nil
+}
+
+func runID(
+	ctx context.Context,
+	settings *node.Settings,
+	args []string,
+) (err error) {
+	flags := flag.NewFlagSet("id", flag.ContinueOnError)
+	owner := flags.Bool(
+		"owner",
+		false,
+		"print the registrant of a base58 ID instead of allocating one",
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: id <registrant> | id -owner <id>")
+	}
+
+	var id []byte
+	if *owner {
+		if id, err = node.ParseID(flags.Arg(0)); err != nil {
+			return err
+		}
+	}
+
+	n := node.New(settings)
+	defer stopNode(n, &err)
+	if err := n.Start(ctx); err != nil {
+		return err
+	}
+
+	if *owner {
+		registrant, err := n.IDOwner(id)
+		if err != nil {
+			return err
+		}
+		if len(registrant) == 0 {
+			return fmt.Errorf("ID %s is not allocated", flags.Arg(0))
+		}
+		fmt.Println(registrant)
+		return nil
+	}
+
+	if id, err = n.AllocateID(flags.Arg(0)); err != nil {
+		return fmt.Errorf("cannot allocate ID: %s", err)
+	}
+	if _, err := n.Commit(); err != nil {
+		return fmt.Errorf("cannot commit: %s", err)
+	}
+
+	fmt.Println(node.FormatID(id))
+
+	return nil
+}
+
+func runDemo(ctx context.Context, settings *node.Settings, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: demo")
+	}
+
+	log.Println("Setting up IPFS node ...")
+	n := node.New(settings)
+	if err := n.Start(ctx); err != nil {
+		n.Stop()
+		return err
+	}
+	log.Println("IPFS node is created")
+
+	id, err := n.AllocateID(demoRegistrant)
+	if err != nil {
+		n.Stop()
+		return fmt.Errorf("cannot allocate ID: %s", err)
+	}
+
+	r := record.NewRegistration()
+	addEntities(r)
+	addRights(r)
+	addStakeholders(r)
+	addContent(r)
+	addKernel(r, id)
+
+	log.Printf("Registering demo blocks ...")
+	ipfs := n.API()
+	m, err := r.Register(ctx, ipfs.Dag().Pinning())
+	if err != nil {
+		n.Stop()
+		return fmt.Errorf("cannot register demo: %s", err)
+	}
+
+	blocks := map[string][]iscn.IscnObject{}
+	for i, obj := range m.Objects() {
+		kind := m.Blocks[i].Kind
+		log.Printf("New %s block %s", kind, obj.RawData())
+		blocks[kind] = append(blocks[kind], obj)
+	}
+
+	testEntity(ctx, ipfs, blocks[record.KindEntity])
+	testRights(ctx, ipfs, blocks[record.KindRights][0])
+	testStakeholders(ctx, ipfs, blocks[record.KindStakeholders][0])
+	testContent(ctx, ipfs, blocks[record.KindContent])
+	testIscnKernel(ctx, ipfs, id, blocks[record.KindKernel][0])
+
+	if _, err := n.Commit(); err != nil {
+		n.Stop()
+		return fmt.Errorf("cannot commit: %s", err)
+	}
+
+	waitForSignal()
+
+	return n.Stop()
+}
@@ -4,57 +4,40 @@ import (
 	"context"
 	"log"
 
+	"github.com/likecoin/iscn-poc/record"
 	"github.com/tidwall/pretty"
 
 	icore "github.com/ipfs/interface-go-ipfs-core"
 	iscn "github.com/likecoin/iscn-ipld/plugin/block"
 )
 
-func testContent(ctx context.Context, ipfs icore.CoreAPI) iscn.IscnObject {
-	// --------------------------------------------------
-	log.Printf("Generating content v1 block ...")
-	data := map[string]interface{}{
-		"type":        "article",
-		"version":     1,
-		"parent":      nil,
-		"source":      "https://example.com/index.html",
-		"edition":     "v.0.1",
-		"fingerprint": "hash://sha256/9f86d081884c7d659a2feaa0",
-		"title":       "Hello World!!!",
-		"description": "Just to say hello to world.",
-		"tags":        []string{"hello", "world", "blog"},
-	}
-
-	b1, err := iscn.Encode(iscn.CodecContent, 1, data)
-	if err != nil {
-		log.Panicf("Cannot create content v1 block: %s", err)
-	}
-	log.Printf("New content v1 block %s", b1.RawData())
-
-	log.Printf("Generating content v2 block ...")
-	data = map[string]interface{}{
-		"type":        "article",
-		"version":     2,
-		"parent":      b1.Cid(),
-		"fingerprint": "hash://sha256/9f86d081884c7d659a2feaa0",
-		"title":       "Hello World!!!",
-	}
-
-	b2, err := iscn.Encode(iscn.CodecContent, 1, data)
-	if err != nil {
-		log.Panicf("Cannot create content v2 block: %s", err)
-	}
-	log.Printf("New content v2 block %s", b2.RawData())
-
-	// --------------------------------------------------
-	log.Printf("Pinning content blocks ...")
+// addContent adds the demo content versions, the version 2 gets the version 1
+// as its parent.
+func addContent(r *record.Registration) {
+	r.Content(record.Content{
+		Type:        "article",
+		Version:     1,
+		Source:      "https://example.com/index.html",
+		Edition:     "v.0.1",
+		Fingerprint: "hash://sha256/9f86d081884c7d659a2feaa0",
+		Title:       "Hello World!!!",
+		Description: "Just to say hello to world.",
+		Tags:        []string{"hello", "world", "blog"},
+	})
+	r.Content(record.Content{
+		Type:        "article",
+		Version:     2,
+		Fingerprint: "hash://sha256/9f86d081884c7d659a2feaa0",
+		Title:       "Hello World!!!",
+	})
+}
 
-	if err := ipfs.Dag().Pinning().Add(ctx, b1); err != nil {
-		log.Panicf("Cannot pin IPLD: %s", err)
-	}
-	if err := ipfs.Dag().Pinning().Add(ctx, b2); err != nil {
-		log.Panicf("Cannot pin IPLD: %s", err)
-	}
+func testContent(
+	ctx context.Context,
+	ipfs icore.CoreAPI,
+	blocks []iscn.IscnObject,
+) {
+	b1, b2 := blocks[0], blocks[1]
 
 	// --------------------------------------------------
 	log.Printf("Getting content blocks ...")
@@ -62,7 +45,6 @@ func testContent(ctx context.Context, ipfs icore.CoreAPI) iscn.IscnObject {
 	ret1, err := ipfs.Dag().Get(ctx, b1.Cid())
 	if err != nil {
 		log.Panicf("Cannot fetch IPLD: %s", err)
-		return nil
 	}
 
 	obj1, err := iscn.Decode(ret1.RawData(), b1.Cid())
@@ -73,7 +55,6 @@ func testContent(ctx context.Context, ipfs icore.CoreAPI) iscn.IscnObject {
 	ret2, err := ipfs.Dag().Get(ctx, b2.Cid())
 	if err != nil {
 		log.Panicf("Cannot fetch IPLD: %s", err)
-		return nil
 	}
 
 	obj2, err := iscn.Decode(ret2.RawData(), b2.Cid())
@@ -99,57 +80,24 @@ func testContent(ctx context.Context, ipfs icore.CoreAPI) iscn.IscnObject {
 	log.Printf("  Type: %s", obj1.GetName())
 	log.Printf("  Schema version: %d", obj1.GetVersion())
 
-	if val, err := obj1.GetString("type"); err == nil {
-		log.Printf("  Content type: %q", val)
-	} else {
+	content1 := record.Content{}
+	if err := content1.FromObject(obj1); err != nil {
 		log.Panicf("%s", err)
 	}
 
-	if val, err := obj1.GetUint64("version"); err == nil {
-		log.Printf("  Version: %d", val)
-	} else {
-		log.Panicf("%s", err)
-	}
+	log.Printf("  Content type: %q", content1.Type)
+	log.Printf("  Version: %d", content1.Version)
 
-	if _, err := obj1.GetCid("parent"); err == nil {
+	if content1.Parent.Defined() {
 		log.Panic("Should not have property \"parent\"")
 	}
 
-	if val, err := obj1.GetString("source"); err == nil {
-		log.Printf("  Source: %q", val)
-	} else {
-		log.Panicf("%s", err)
-	}
-
-	if val, err := obj1.GetString("edition"); err == nil {
-		log.Printf("  Edition: %q", val)
-	} else {
-		log.Panicf("%s", err)
-	}
-
-	if val, err := obj1.GetString("fingerprint"); err == nil {
-		log.Printf("  Fingerprint: %q", val)
-	} else {
-		log.Panicf("%s", err)
-	}
-
-	if val, err := obj1.GetString("title"); err == nil {
-		log.Printf("  Title: %q", val)
-	} else {
-		log.Panicf("%s", err)
-	}
-
-	if val, err := obj1.GetString("description"); err == nil {
-		log.Printf("  Description: %q", val)
-	} else {
-		log.Panicf("%s", err)
-	}
-
-	if val, err := obj1.GetArray("tags"); err == nil {
-		log.Printf("  Tags: %v", val)
-	} else {
-		log.Panicf("%s", err)
-	}
+	log.Printf("  Source: %q", content1.Source)
+	log.Printf("  Edition: %q", content1.Edition)
+	log.Printf("  Fingerprint: %q", content1.Fingerprint)
+	log.Printf("  Title: %q", content1.Title)
+	log.Printf("  Description: %q", content1.Description)
+	log.Printf("  Tags: %v", content1.Tags)
 
 	log.Printf("Content version 2")
 	c2, err := b2.Cid().StringOfBase('z')
@@ -163,53 +111,39 @@ func testContent(ctx context.Context, ipfs icore.CoreAPI) iscn.IscnObject {
 	log.Printf("  Type: %s", obj2.GetName())
 	log.Printf("  Schema version: %d", obj2.GetVersion())
 
-	if val, err := obj2.GetString("type"); err == nil {
-		log.Printf("  Content type: %q", val)
-	} else {
+	content2 := record.Content{}
+	if err := content2.FromObject(obj2); err != nil {
 		log.Panicf("%s", err)
 	}
 
-	if val, err := obj2.GetUint64("version"); err == nil {
-		log.Printf("  Version: %d", val)
-	} else {
-		log.Panicf("%s", err)
-	}
+	log.Printf("  Content type: %q", content2.Type)
+	log.Printf("  Version: %d", content2.Version)
 
-	if val, err := obj2.GetCid("parent"); err == nil {
-		c, err := val.StringOfBase('z')
-		if err != nil {
-			log.Panicf("Cannot retrieve CID for parent block: %s", err)
-		}
-		log.Printf("  Parent: %s", c)
-	} else {
-		log.Panicf("%s", err)
+	if !content2.Parent.Defined() {
+		log.Panic("Should have property \"parent\"")
 	}
+	parent, err := content2.Parent.StringOfBase('z')
+	if err != nil {
+		log.Panicf("Cannot retrieve CID for parent block: %s", err)
+	}
+	log.Printf("  Parent: %s", parent)
 
-	if _, err := obj2.GetString("source"); err == nil {
+	if len(content2.Source) > 0 {
 		log.Panic("Should not have property \"source\"")
 	}
 
-	if _, err := obj2.GetString("edition"); err == nil {
+	if len(content2.Edition) > 0 {
 		log.Panic("Should not have property \"edition\"")
 	}
 
-	if val, err := obj2.GetString("fingerprint"); err == nil {
-		log.Printf("  Fingerprint: %q", val)
-	} else {
-		log.Panicf("%s", err)
-	}
-
-	if val, err := obj2.GetString("title"); err == nil {
-		log.Printf("  Title: %q", val)
-	} else {
-		log.Panicf("%s", err)
-	}
+	log.Printf("  Fingerprint: %q", content2.Fingerprint)
+	log.Printf("  Title: %q", content2.Title)
 
-	if _, err := obj2.GetString("description"); err == nil {
+	if len(content2.Description) > 0 {
 		log.Panic("Should not have property \"description\"")
 	}
 
-	if _, err := obj2.GetArray("tags"); err == nil {
+	if len(content2.Tags) > 0 {
 		log.Panic("Should not have property \"tags\"")
 	}
 
@@ -237,5 +171,17 @@ func testContent(ctx context.Context, ipfs icore.CoreAPI) iscn.IscnObject {
 	log.Println(string(json2))
 	log.Println(string(pretty.Pretty([]byte(json2))))
 
-	return b2
+	// --------------------------------------------------
+	// Diff
+
+	log.Println()
+	log.Println("********************************************************************************")
+	log.Println("Content diff")
+	log.Println("********************************************************************************")
+
+	changes, err := record.Diff(obj1, obj2)
+	if err != nil {
+		log.Panicf("Cannot diff content blocks: %s", err)
+	}
+	log.Println(changes)
 }
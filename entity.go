@@ -4,62 +4,36 @@ import (
 	"context"
 	"log"
 
+	"github.com/likecoin/iscn-poc/record"
 	"github.com/tidwall/pretty"
 
 	icore "github.com/ipfs/interface-go-ipfs-core"
 	iscn "github.com/likecoin/iscn-ipld/plugin/block"
 )
 
-func testEntity(ctx context.Context, ipfs icore.CoreAPI) []iscn.IscnObject {
-	// --------------------------------------------------
-	log.Printf("Generating entity block 1 ...")
-	data := map[string]interface{}{
-		"id":          "lcc://id/comsos1xxxxxxxxxxxxxxxxxxxxxx",
-		"name":        "Alice",
-		"description": "I am the Alice.",
-	}
-
-	b1, err := iscn.Encode(iscn.CodecEntity, 1, data)
-	if err != nil {
-		log.Panicf("Cannot create entity block 1: %s", err)
-	}
-	log.Printf("New entity block 1 %s", b1.RawData())
-
-	log.Printf("Generating entity block 2 ...")
-	data = map[string]interface{}{
-		"id": "lcc://id/comsos1yyyyyyyyyyyyyyyyyyyyyy",
-	}
-
-	b2, err := iscn.Encode(iscn.CodecEntity, 1, data)
-	if err != nil {
-		log.Panicf("Cannot create entity block 2: %s", err)
-	}
-	log.Printf("New entity block 2 %s", b2.RawData())
-
-	log.Printf("Generating entity block 3 ...")
-	data = map[string]interface{}{
-		"id":   "lcc://id/comsos1zzzzzzzzzzzzzzzzzzzzzz",
-		"name": "Calos",
-	}
-
-	b3, err := iscn.Encode(iscn.CodecEntity, 1, data)
-	if err != nil {
-		log.Panicf("Cannot create entity block 3: %s", err)
-	}
-	log.Printf("New entity block 3 %s", b3.RawData())
-
-	// --------------------------------------------------
-	log.Printf("Pinning entity blocks ...")
+// addEntities adds the demo entities, they are referenced by the rights and
+// the stakeholders with "alice", "yyy" and "calos".
+func addEntities(r *record.Registration) {
+	r.Entity("alice", record.Entity{
+		ID:          "lcc://id/comsos1xxxxxxxxxxxxxxxxxxxxxx",
+		Name:        "Alice",
+		Description: "I am the Alice.",
+	})
+	r.Entity("yyy", record.Entity{
+		ID: "lcc://id/comsos1yyyyyyyyyyyyyyyyyyyyyy",
+	})
+	r.Entity("calos", record.Entity{
+		ID:   "lcc://id/comsos1zzzzzzzzzzzzzzzzzzzzzz",
+		Name: "Calos",
+	})
+}
 
-	if err := ipfs.Dag().Pinning().Add(ctx, b1); err != nil {
-		log.Panicf("Cannot pin IPLD: %s", err)
-	}
-	if err := ipfs.Dag().Pinning().Add(ctx, b2); err != nil {
-		log.Panicf("Cannot pin IPLD: %s", err)
-	}
-	if err := ipfs.Dag().Pinning().Add(ctx, b3); err != nil {
-		log.Panicf("Cannot pin IPLD: %s", err)
-	}
+func testEntity(
+	ctx context.Context,
+	ipfs icore.CoreAPI,
+	blocks []iscn.IscnObject,
+) {
+	b1, b2, b3 := blocks[0], blocks[1], blocks[2]
 
 	// --------------------------------------------------
 	log.Printf("Getting entity blocks ...")
@@ -67,7 +41,6 @@ func testEntity(ctx context.Context, ipfs icore.CoreAPI) []iscn.IscnObject {
 	ret1, err := ipfs.Dag().Get(ctx, b1.Cid())
 	if err != nil {
 		log.Panicf("Cannot fetch IPLD: %s", err)
-		return nil
 	}
 
 	obj1, err := iscn.Decode(ret1.RawData(), b1.Cid())
@@ -78,7 +51,6 @@ func testEntity(ctx context.Context, ipfs icore.CoreAPI) []iscn.IscnObject {
 	ret2, err := ipfs.Dag().Get(ctx, b2.Cid())
 	if err != nil {
 		log.Panicf("Cannot fetch IPLD: %s", err)
-		return nil
 	}
 
 	obj2, err := iscn.Decode(ret2.RawData(), b2.Cid())
@@ -89,7 +61,6 @@ func testEntity(ctx context.Context, ipfs icore.CoreAPI) []iscn.IscnObject {
 	ret3, err := ipfs.Dag().Get(ctx, b3.Cid())
 	if err != nil {
 		log.Panicf("Cannot fetch IPLD: %s", err)
-		return nil
 	}
 
 	obj3, err := iscn.Decode(ret3.RawData(), b3.Cid())
@@ -115,23 +86,14 @@ func testEntity(ctx context.Context, ipfs icore.CoreAPI) []iscn.IscnObject {
 	log.Printf("  Type: %s", obj1.GetName())
 	log.Printf("  Schema version: %d", obj1.GetVersion())
 
-	if val, err := obj1.GetString("id"); err == nil {
-		log.Printf("  ID: %q", val)
-	} else {
+	entity1 := record.Entity{}
+	if err := entity1.FromObject(obj1); err != nil {
 		log.Panicf("%s", err)
 	}
 
-	if val, err := obj1.GetString("name"); err == nil {
-		log.Printf("  Name: %q", val)
-	} else {
-		log.Panicf("%s", err)
-	}
-
-	if val, err := obj1.GetString("description"); err == nil {
-		log.Printf("  Description: %q", val)
-	} else {
-		log.Panicf("%s", err)
-	}
+	log.Printf("  ID: %q", entity1.ID)
+	log.Printf("  Name: %q", entity1.Name)
+	log.Printf("  Description: %q", entity1.Description)
 
 	log.Printf("Entity 2")
 	c2, err := b2.Cid().StringOfBase('z')
@@ -145,17 +107,18 @@ func testEntity(ctx context.Context, ipfs icore.CoreAPI) []iscn.IscnObject {
 	log.Printf("  Type: %s", obj2.GetName())
 	log.Printf("  Schema version: %d", obj2.GetVersion())
 
-	if val, err := obj2.GetString("id"); err == nil {
-		log.Printf("  ID: %q", val)
-	} else {
+	entity2 := record.Entity{}
+	if err := entity2.FromObject(obj2); err != nil {
 		log.Panicf("%s", err)
 	}
 
-	if _, err := obj2.GetString("name"); err == nil {
+	log.Printf("  ID: %q", entity2.ID)
+
+	if len(entity2.Name) > 0 {
 		log.Panic("Should not have property \"name\"")
 	}
 
-	if _, err := obj2.GetString("description"); err == nil {
+	if len(entity2.Description) > 0 {
 		log.Panic("Should not have property \"description\"")
 	}
 
@@ -171,19 +134,15 @@ func testEntity(ctx context.Context, ipfs icore.CoreAPI) []iscn.IscnObject {
 	log.Printf("  Type: %s", obj3.GetName())
 	log.Printf("  Schema version: %d", obj3.GetVersion())
 
-	if val, err := obj3.GetString("id"); err == nil {
-		log.Printf("  ID: %q", val)
-	} else {
+	entity3 := record.Entity{}
+	if err := entity3.FromObject(obj3); err != nil {
 		log.Panicf("%s", err)
 	}
 
-	if val, err := obj3.GetString("name"); err == nil {
-		log.Printf("  Name: %q", val)
-	} else {
-		log.Panicf("%s", err)
-	}
+	log.Printf("  ID: %q", entity3.ID)
+	log.Printf("  Name: %q", entity3.Name)
 
-	if _, err := obj3.GetString("description"); err == nil {
+	if len(entity3.Description) > 0 {
 		log.Panic("Should not have property \"description\"")
 	}
 
@@ -218,6 +177,4 @@ func testEntity(ctx context.Context, ipfs icore.CoreAPI) []iscn.IscnObject {
 	}
 	log.Println(string(json3))
 	log.Println(string(pretty.Pretty([]byte(json3))))
-
-	return []iscn.IscnObject{b1, b2, b3}
 }
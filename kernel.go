@@ -4,59 +4,67 @@ import (
 	"bytes"
 	"context"
 	"log"
-	"math/rand"
+	"time"
 
 	"github.com/btcsuite/btcutil/base58"
+	"github.com/ipfs/go-cid"
+	"github.com/likecoin/iscn-poc/record"
 	"github.com/tidwall/pretty"
 
 	icore "github.com/ipfs/interface-go-ipfs-core"
 	iscn "github.com/likecoin/iscn-ipld/plugin/block"
 )
 
-func testIscnKernel(
-	ctx context.Context,
-	ipfs icore.CoreAPI,
-	rights iscn.IscnObject,
-	stakeholders iscn.IscnObject,
-	content iscn.IscnObject,
-) {
-	// --------------------------------------------------
-	log.Printf("Generating ISCN kernel block ...")
-
-	id := make([]byte, 32)
-	rand.Read(id)
-
-	data := map[string]interface{}{
-		"id":           id,
-		"timestamp":    "2020-01-01T12:34:56Z",
-		"version":      1,
-		"rights":       rights.Cid(),
-		"stakeholders": stakeholders.Cid(),
-		"content":      content.Cid(),
-		"zzz":          -987654321,
-		"yyy":          []string{"abc", "def", "ghi"},
-		"xxx":          []byte{'x', 'y', 'z'},
-		"p": map[string]interface{}{
-			"a": 10,
-			"b": map[string]interface{}{
-				"ba": "abc",
-				"bb": 123,
+// demoNamespace is the namespace of the registered custom properties of the
+// demo kernel.
+const demoNamespace = "demo"
+
+func init() {
+	record.RegisterNamespace(demoNamespace, map[string]record.Property{
+		"xxx": {Type: record.TypeBytes},
+		"p": {
+			Type: record.TypeObject,
+			Properties: map[string]record.Property{
+				"a": {Type: record.TypeInt},
+				"b": {
+					Type: record.TypeObject,
+					Properties: map[string]record.Property{
+						"ba": {Type: record.TypeString},
+						"bb": {Type: record.TypeInt, Required: true},
+					},
+				},
 			},
 		},
-	}
-
-	b, err := iscn.Encode(iscn.CodecISCN, 1, data)
-	if err != nil {
-		log.Panicf("Cannot create ISCN kernel block: %s", err)
-	}
-
-	// --------------------------------------------------
-	log.Printf("Pinning ISCN kernel block ...")
+	})
+}
 
-	if err := ipfs.Dag().Pinning().Add(ctx, b); err != nil {
-		log.Panicf("Cannot pin IPLD: %s", err)
-	}
+// addKernel sets the demo kernel with the ID and the custom properties.
+func addKernel(r *record.Registration, id []byte) {
+	r.Kernel(record.Kernel{
+		ID:        id,
+		Timestamp: time.Date(2020, 1, 1, 12, 34, 56, 0, time.UTC),
+		Version:   1,
+		Custom: map[string]interface{}{
+			"zzz":                  -987654321,
+			"yyy":                  []string{"abc", "def", "ghi"},
+			demoNamespace + ":xxx": []byte{'x', 'y', 'z'},
+			demoNamespace + ":p": map[string]interface{}{
+				"a": 10,
+				"b": map[string]interface{}{
+					"ba": "abc",
+					"bb": 123,
+				},
+			},
+		},
+	})
+}
 
+func testIscnKernel(
+	ctx context.Context,
+	ipfs icore.CoreAPI,
+	id []byte,
+	b iscn.IscnObject,
+) {
 	// --------------------------------------------------
 	log.Printf("Getting ISCN kernel block ...")
 
@@ -87,64 +95,48 @@ func testIscnKernel(
 	log.Printf("  Type: %s", obj.GetName())
 	log.Printf("  Schema version: %d", obj.GetVersion())
 
-	if val, err := obj.GetBytes("id"); err == nil {
-		if !bytes.Equal(id, val) {
-			log.Panic("ID is not matched")
-		}
-
-		log.Printf("  ID (original): %s", base58.Encode(id))
-		log.Printf("  ID           : %s", base58.Encode(val))
-	} else {
+	kernel := record.Kernel{}
+	if err := kernel.FromObject(obj); err != nil {
 		log.Panicf("%s", err)
 	}
 
-	if val, err := obj.GetString("timestamp"); err == nil {
-		log.Printf("  Timestamp: %q", val)
-	} else {
-		log.Panicf("%s", err)
+	if !bytes.Equal(id, kernel.ID) {
+		log.Panic("ID is not matched")
 	}
-
-	if val, err := obj.GetUint64("version"); err == nil {
-		log.Printf("  Version: %d", val)
-	} else {
-		log.Panicf("%s", err)
+	log.Printf("  ID (original): %s", base58.Encode(id))
+	log.Printf("  ID           : %s", base58.Encode(kernel.ID))
+
+	log.Printf("  Timestamp: %s", kernel.Timestamp.Format(time.RFC3339))
+	log.Printf("  Version: %d", kernel.Version)
+
+	links := []struct {
+		name string
+		cid  cid.Cid
+	}{
+		{"Rights", kernel.Rights},
+		{"Stakeholders", kernel.Stakeholders},
+		{"Content", kernel.Content},
 	}
-
-	if val, err := obj.GetCid("rights"); err == nil {
-		c, err := val.StringOfBase('z')
+	for _, link := range links {
+		c, err := link.cid.StringOfBase('z')
 		if err != nil {
 			log.Panicf("Cannot retrieve CID from block: %s", err)
 		}
-		log.Printf("  Rights: %s (0x%x)", c, val.Type())
-	} else {
-		log.Panicf("%s", err)
-	}
-
-	if val, err := obj.GetCid("stakeholders"); err == nil {
-		c, err := val.StringOfBase('z')
-		if err != nil {
-			log.Panicf("Cannot retrieve CID from block: %s", err)
-		}
-		log.Printf("  Stakeholders: %s (0x%x)", c, val.Type())
-	} else {
-		log.Panicf("%s", err)
-	}
-
-	if val, err := obj.GetCid("content"); err == nil {
-		c, err := val.StringOfBase('z')
-		if err != nil {
-			log.Panicf("Cannot retrieve CID from block: %s", err)
-		}
-		log.Printf("  Content: %s (0x%x)", c, val.Type())
-	} else {
-		log.Panicf("%s", err)
+		log.Printf("  %s: %s (0x%x)", link.name, c, link.cid.Type())
 	}
 
 	log.Println("  Custom properties:")
-	for key, value := range obj.GetCustom() {
+	for key, value := range kernel.Custom {
 		log.Printf("    %q:", key)
 		log.Printf("      %T -> %v", value, value)
 	}
+	log.Printf("  Unregistered custom properties: %v", kernel.Custom.Unregistered())
+
+	bb, err := kernel.Custom.GetInt(demoNamespace + ":p.b.bb")
+	if err != nil {
+		log.Panicf("Cannot read custom property: %s", err)
+	}
+	log.Printf("  %s:p.b.bb: %d", demoNamespace, bb)
 
 	// --------------------------------------------------
 	// JSON
@@ -2,194 +2,127 @@ package main
 
 import (
 	"context"
-	"io/ioutil"
+	"flag"
+	"fmt"
 	"log"
-	"math/rand"
 	"os"
 	"os/signal"
-	"path/filepath"
+	"strings"
 	"syscall"
-	"time"
 
-	"github.com/cosmos/cosmos-sdk/store"
-	"github.com/ipfs/go-ipfs/core"
-	"github.com/ipfs/go-ipfs/core/coreapi"
-	"github.com/ipfs/go-ipfs/plugin/loader"
-	"github.com/ipfs/go-ipfs/plugin/plugins/cosmosds"
-	"github.com/ipfs/go-ipfs/repo/fsrepo"
-
-	cosmos "github.com/cosmos/cosmos-sdk/types"
-	config "github.com/ipfs/go-ipfs-config"
-	libp2p "github.com/ipfs/go-ipfs/core/node/libp2p"
-	icore "github.com/ipfs/interface-go-ipfs-core"
-	abci "github.com/tendermint/tendermint/abci/types"
-	tlog "github.com/tendermint/tendermint/libs/log"
+	"github.com/likecoin/iscn-poc/node"
+	"github.com/likecoin/iscn-poc/record"
 )
 
-func setupCosmosStore(plugins *loader.PluginLoader) cosmos.CommitMultiStore {
-	pl, err := plugins.GetPlugin("ds-cosmos")
-	if err != nil {
-		log.Panicf("Cannot retrieve \"ds-cosmos\" plugin: %s", err)
-	}
-
-	cosmosDSPlugin, ok := pl.(*cosmosds.Plugin)
-	if !ok {
-		log.Panic("The plugin is not a \"*cosmosds.Plugin\"")
-	}
-
-	dataDir := filepath.Join(".", "cosmos")
-	db, err := cosmos.NewLevelDB("application", dataDir)
-	if err != nil {
-		log.Panicf("Failed to create LevelDB: %s", err)
-	}
-
-	key := cosmos.NewKVStoreKey("StoreKey")
-	cms := store.NewCommitMultiStore(db)
-	cms.MountStoreWithDB(key, cosmos.StoreTypeIAVL, db)
-	cms.LoadLatestVersion()
-	ctx := cosmos.NewContext(cms, abci.Header{}, false, tlog.NewNopLogger())
-	kv := ctx.KVStore(key)
-
-	err = cosmosDSPlugin.SetCosmosStore(kv)
-	if err != nil {
-		log.Panicf("Cannot setup Cosmos store: %s", err)
-	}
-
-	return cms
+func waitForSignal() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigs
+	log.Printf("\nGet signal: \"%v\"", sig)
 }
 
-func setupDefaultDatastoreConfig(cfg *config.Config) *config.Config {
-	cfg.Datastore.Spec = map[string]interface{}{
-		"mountpoint": "/",
-		"type":       "measure",
-		"prefix":     "cosmossdk.datastore",
-		"child": map[string]interface{}{
-			"type":        "cosmosds",
-			"path":        "datastore",
-			"compression": "none",
-		},
-	}
-	return cfg
-}
-
-func setupNode(ctx context.Context) (
-	*loader.PluginLoader,
-	icore.CoreAPI,
-	cosmos.CommitMultiStore,
-	error) {
-	rootPath, err := filepath.Abs("./ipfs")
-	if err != nil {
-		log.Printf("Cannot parse path: %s", err)
-		return nil, nil, nil, err
-	}
-
-	plugins, err := loader.NewPluginLoader(rootPath)
-	if err != nil {
-		log.Printf("error loading plugins: %s", err)
-		return nil, nil, nil, err
-	}
-
-	if err := plugins.Initialize(); err != nil {
-		log.Printf("error initializing plugins: %s", err)
-		return nil, nil, nil, err
-	}
-
-	if err := plugins.Inject(); err != nil {
-		log.Printf("error initializing plugins: %s", err)
-		return nil, nil, nil, err
-	}
-
-	rootPath, err = config.Path(rootPath, "")
-	if err != nil {
-		log.Printf("Cannot set config path: %s", err)
-		return nil, nil, nil, err
-	}
-
-	cfg, err := config.Init(ioutil.Discard, 2048)
-	if err != nil {
-		log.Printf("Cannot init config: %s", err)
-		return nil, nil, nil, err
-	}
-	cfg = setupDefaultDatastoreConfig(cfg)
-
-	err = fsrepo.Init(rootPath, cfg)
-	if err != nil {
-		log.Printf("Cannot init repo: %s", "ab")
-		return nil, nil, nil, err
-	}
-
-	repo, err := fsrepo.Open(rootPath)
-	if err != nil {
-		log.Printf("Cannot open repo: %s", err)
-		return nil, nil, nil, err
-	}
-
-	nodeOptions := &core.BuildCfg{
-		Online:  true,
-		Routing: libp2p.DHTOption,
-		Repo:    repo,
-	}
-
-	node, err := core.NewNode(ctx, nodeOptions)
-	if err != nil {
-		log.Printf("Cannot create node: %s", err)
-		return nil, nil, nil, err
-	}
-	node.IsDaemon = true
-
-	ipfs, err := coreapi.NewCoreAPI(node)
-	if err != nil {
-		log.Println("No IPFS repo available on the default path")
-		return nil, nil, nil, err
-	}
-
-	log.Println("Start plugin")
-	err = plugins.Start(node)
-	if err != nil {
-		log.Printf("Cannot start plugins: %s", err)
-		return nil, nil, nil, err
-	}
-
-	cms := setupCosmosStore(plugins)
-
-	return plugins, ipfs, cms, nil
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: %s [options] <command> [arguments]
+
+Commands:
+  init                   Initialize the IPFS and Cosmos repos
+  daemon                 Run the ISCN node
+  add [-registrant <r>] <codec> <file>
+                         Register an ISCN block from a JSON file, a kernel
+                         ID is recorded for the registrant and refused if it
+                         belongs to another one
+  get [-version <v>] <cid or path>
+                         Print an ISCN block as JSON, or the value of a path
+                         such as /<cid>/rights/1/period/from, optionally as
+                         of a past version of the Cosmos store
+  diff [-json] <cid> <cid>
+                         Print the changed fields between two ISCN blocks
+  history <cid>          Print the versions of a content and its ancestors
+  resolve [-depth <n>] <cid>
+                         Print a kernel as JSON with the linked blocks inlined
+  export <cid> > <file>  Write a kernel and its linked blocks as a CAR file
+  import <file>          Store and pin the blocks of a CAR file
+  status                 Print the last commit of the Cosmos store
+  id <registrant>        Allocate the next ISCN ID of a registrant
+  id -owner <id>         Print the registrant of an ISCN ID
+  migrate [-to <v>] [-rewrite]
+                         Report the blocks of an older schema version, or
+                         rewrite them and print the old and the new CIDs
+  schema [-version <v>] <codec>
+                         Print the JSON Schema of the blocks of a codec
+  demo                   Run the demo registration flow
+
+Codecs: %s
+
+Options:
+`, os.Args[0], strings.Join(record.CodecNames(), ", "))
+	flag.PrintDefaults()
 }
 
 func main() {
-	rand.Seed(time.Now().UnixNano())
+	settingsPath := flag.String(
+		"config",
+		os.Getenv(node.EnvPrefix+"CONFIG"),
+		"path of the YAML settings file",
+	)
+	offline := flag.Bool("offline", false, "run the node without networking")
 
-	sigs := make(chan os.Signal, 1)
-	done := make(chan bool, 1)
-
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		sig := <-sigs
-		log.Printf("\nGet signal: \"%v\"", sig)
-		done <- true
-	}()
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	log.Println("Setting up IPFS node ...")
-	plugins, ipfs, cms, err := setupNode(ctx)
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+
+	settings, err := node.LoadSettings(*settingsPath)
 	if err != nil {
-		log.Panicf("Failed to set up IPFS node")
+		log.Fatalf("Cannot load settings: %s", err)
+	}
+	if *offline {
+		settings.Offline = true
+	}
+
+	switch cmd {
+	case "init":
+		err = runInit(ctx, settings, args)
+	case "daemon":
+		err = runDaemon(ctx, settings, args)
+	case "add":
+		err = runAdd(ctx, settings, args)
+	case "get":
+		err = runGet(ctx, settings, args)
+	case "diff":
+		err = runDiff(ctx, settings, args)
+	case "history":
+		err = runHistory(ctx, settings, args)
+	case "resolve":
+		err = runResolve(ctx, settings, args)
+	case "export":
+		err = runExport(ctx, settings, args)
+	case "import":
+		err = runImport(ctx, settings, args)
+	case "status":
+		err = runStatus(ctx, settings, args)
+	case "id":
+		err = runID(ctx, settings, args)
+	case "migrate":
+		err = runMigrate(ctx, settings, args)
+	case "schema":
+		err = runSchema(ctx, settings, args)
+	case "demo":
+		err = runDemo(ctx, settings, args)
+	default:
+		fmt.Fprintf(flag.CommandLine.Output(), "Unknown command %q\n\n", cmd)
+		flag.Usage()
+		os.Exit(2)
 	}
-	log.Println("IPFS node is created")
-
-	entities := testEntity(ctx, ipfs)
-	rights := testRights(ctx, ipfs, entities)
-	stakeholders := testStakeholders(ctx, ipfs, entities)
-	content := testContent(ctx, ipfs)
-	testIscnKernel(ctx, ipfs, rights, stakeholders, content)
-
-	cms.Commit()
 
-	<-done
-	log.Println("Close plugin")
-	err = plugins.Close()
 	if err != nil {
-		log.Panicf("Cannot close plugins: %s", err)
+		log.Fatalf("Command %q failed: %s", cmd, err)
 	}
 }
@@ -0,0 +1,59 @@
+package node
+
+import (
+	"github.com/ipfs/go-cid"
+	"github.com/likecoin/iscn-poc/record"
+
+	cosmos "github.com/cosmos/cosmos-sdk/types"
+	ds "github.com/ipfs/go-datastore"
+	dshelp "github.com/ipfs/go-ipfs-ds-help"
+)
+
+// Blocks lists the CIDs of the ISCN blocks in the Cosmos SDK store.
+func (n *Node) Blocks() ([]cid.Cid, error) {
+	if err := n.acquireStore(); err != nil {
+		return nil, err
+	}
+	defer n.release()
+
+	n.committer.lock.Lock()
+	defer n.committer.lock.Unlock()
+
+	kv := n.cms.GetKVStore(n.storeKey)
+	cids := []cid.Cid{}
+	for _, c := range chainBlocks(kv, chainBlocksPrefix(n.settings.Datastore.Layout)) {
+		if record.IsISCN(c) {
+			cids = append(cids, c)
+		}
+	}
+
+	return cids, nil
+}
+
+// chainBlocks lists the CIDs of the blocks under the prefix of the Cosmos SDK
+// store.
+func chainBlocks(kv cosmos.KVStore, prefix ds.Key) []cid.Cid {
+	start := prefix.String()
+	if !prefix.Equal(ds.NewKey("/")) {
+		start += "/"
+	}
+
+	it := cosmos.KVStorePrefixIterator(kv, []byte(start))
+	defer it.Close()
+
+	cids := []cid.Cid{}
+	for ; it.Valid(); it.Next() {
+		k := ds.RawKey(string(it.Key()))
+		if !prefix.Equal(k.Parent()) {
+			continue
+		}
+
+		c, err := dshelp.DsKeyToCid(ds.NewKey(k.BaseNamespace()))
+		if err != nil {
+			continue
+		}
+		cids = append(cids, c)
+	}
+
+	return cids
+}
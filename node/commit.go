@@ -0,0 +1,245 @@
+package node
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	cosmos "github.com/cosmos/cosmos-sdk/types"
+)
+
+// The reasons of a commit.
+const (
+	CommitOnWrites   = "writes"
+	CommitOnInterval = "interval"
+	CommitOnRequest  = "request"
+	CommitOnShutdown = "shutdown"
+)
+
+// CommitRecord is the result of a commit of the Cosmos SDK store.
+type CommitRecord struct {
+	cosmos.CommitID
+
+	// Reason is one of CommitOnWrites, CommitOnInterval, CommitOnRequest and
+	// CommitOnShutdown.
+	Reason string
+	Time   time.Time
+
+	// Writes is the number of writes included in this commit.
+	Writes int
+}
+
+// committer commits the Cosmos SDK store according to the commit policy of the
+// settings. It also serializes the access to the store as the IAVL store is
+// not safe for concurrent use.
+type committer struct {
+	lock sync.Mutex
+	cms  cosmos.CommitMultiStore
+
+	everyWrites int
+	interval    time.Duration
+
+	writes  int
+	history []CommitRecord
+
+	trigger chan struct{}
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+func newCommitter(cms cosmos.CommitMultiStore, settings *Settings) *committer {
+	return &committer{
+		cms:         cms,
+		everyWrites: settings.Commit.EveryWrites,
+		interval:    settings.Commit.Interval,
+		trigger:     make(chan struct{}, 1),
+		quit:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// wrote counts a write to the store, the lock must be held by the caller.
+func (c *committer) wrote() {
+	c.writes++
+	if c.everyWrites > 0 && c.writes >= c.everyWrites {
+		select {
+		case c.trigger <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (c *committer) commit(reason string) CommitRecord {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	record := CommitRecord{
+		CommitID: c.cms.Commit(),
+		Reason:   reason,
+		Time:     time.Now(),
+		Writes:   c.writes,
+	}
+	c.writes = 0
+	c.history = append(c.history, record)
+
+	log.Printf(
+		"Committed version %d with app hash %X (%s, %d writes)",
+		record.Version,
+		record.Hash,
+		reason,
+		record.Writes,
+	)
+
+	return record
+}
+
+func (c *committer) pending() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.writes
+}
+
+func (c *committer) run() {
+	defer close(c.done)
+
+	var tick <-chan time.Time
+	if c.interval > 0 {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-c.trigger:
+			if c.pending() >= c.everyWrites {
+				c.commit(CommitOnWrites)
+			}
+		case <-tick:
+			if c.pending() > 0 {
+				c.commit(CommitOnInterval)
+			}
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// stop stops the commit loop and commits the store for the last time.
+func (c *committer) stop() CommitRecord {
+	close(c.quit)
+	<-c.done
+	return c.commit(CommitOnShutdown)
+}
+
+func (c *committer) records() []CommitRecord {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	records := make([]CommitRecord, len(c.history))
+	copy(records, c.history)
+	return records
+}
+
+// countingStore is the KV store handed to the cosmosds plugin. It counts the
+// writes for the committer and takes its lock on every access. The iterators
+// copy the keys of their range under the lock and read each value when it is
+// asked for, so the datastore queries never walk the IAVL tree while it is
+// written or committed.
+type countingStore struct {
+	cosmos.KVStore
+	committer *committer
+}
+
+func (s countingStore) Get(key []byte) []byte {
+	s.committer.lock.Lock()
+	defer s.committer.lock.Unlock()
+	return s.KVStore.Get(key)
+}
+
+func (s countingStore) Has(key []byte) bool {
+	s.committer.lock.Lock()
+	defer s.committer.lock.Unlock()
+	return s.KVStore.Has(key)
+}
+
+func (s countingStore) Set(key, value []byte) {
+	s.committer.lock.Lock()
+	defer s.committer.lock.Unlock()
+	s.KVStore.Set(key, value)
+	s.committer.wrote()
+}
+
+func (s countingStore) Delete(key []byte) {
+	s.committer.lock.Lock()
+	defer s.committer.lock.Unlock()
+	s.KVStore.Delete(key)
+	s.committer.wrote()
+}
+
+func (s countingStore) Iterator(start, end []byte) cosmos.Iterator {
+	s.committer.lock.Lock()
+	defer s.committer.lock.Unlock()
+	return copyIterator(s, s.KVStore.Iterator(start, end))
+}
+
+func (s countingStore) ReverseIterator(start, end []byte) cosmos.Iterator {
+	s.committer.lock.Lock()
+	defer s.committer.lock.Unlock()
+	return copyIterator(s, s.KVStore.ReverseIterator(start, end))
+}
+
+// copiedIterator iterates over the keys copied from another iterator. The
+// values are read from the store when they are asked for, a value is nil if
+// its key has been deleted since the copy.
+type copiedIterator struct {
+	store      cosmos.KVStore
+	start, end []byte
+	keys       [][]byte
+}
+
+// copyIterator copies the remaining keys of the iterator and closes it, the
+// values are read from the store later.
+func copyIterator(store cosmos.KVStore, it cosmos.Iterator) *copiedIterator {
+	defer it.Close()
+
+	copied := &copiedIterator{store: store}
+	copied.start, copied.end = it.Domain()
+	for ; it.Valid(); it.Next() {
+		copied.keys = append(copied.keys, it.Key())
+	}
+	return copied
+}
+
+func (it *copiedIterator) Domain() ([]byte, []byte) {
+	return it.start, it.end
+}
+
+func (it *copiedIterator) Valid() bool {
+	return len(it.keys) > 0
+}
+
+func (it *copiedIterator) Next() {
+	if !it.Valid() {
+		panic("iterator is not valid")
+	}
+	it.keys = it.keys[1:]
+}
+
+func (it *copiedIterator) Key() []byte {
+	if !it.Valid() {
+		panic("iterator is not valid")
+	}
+	return it.keys[0]
+}
+
+func (it *copiedIterator) Value() []byte {
+	if !it.Valid() {
+		panic("iterator is not valid")
+	}
+	return it.store.Get(it.keys[0])
+}
+
+func (it *copiedIterator) Close() {
+	it.keys = nil
+}
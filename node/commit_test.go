@@ -0,0 +1,145 @@
+package node
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// waitCommits waits until the committer has made n commits.
+func waitCommits(t *testing.T, c *committer, n int) []CommitRecord {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		records := c.records()
+		if len(records) >= n {
+			return records
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d commits are made, want %d", len(records), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newTestCommitter(
+	t *testing.T,
+	everyWrites int,
+	interval time.Duration,
+) (*committer, countingStore) {
+	t.Helper()
+
+	s := newTestStore(t)
+	settings := DefaultSettings()
+	settings.Commit.EveryWrites = everyWrites
+	settings.Commit.Interval = interval
+
+	c := newCommitter(s.cms, settings)
+	return c, countingStore{KVStore: s.cms.GetKVStore(s.storeKey), committer: c}
+}
+
+func TestCommitOnWrites(t *testing.T) {
+	c, kv := newTestCommitter(t, 3, 0)
+	go c.run()
+
+	for i := 0; i < 3; i++ {
+		kv.Set([]byte(fmt.Sprintf("key%d", i)), []byte("value"))
+	}
+	records := waitCommits(t, c, 1)
+	if records[0].Reason != CommitOnWrites || records[0].Writes != 3 {
+		t.Errorf("commit is on %s of %d writes, want on writes of 3", records[0].Reason, records[0].Writes)
+	}
+
+	kv.Delete([]byte("key0"))
+	last := c.stop()
+	if last.Reason != CommitOnShutdown || last.Writes != 1 {
+		t.Errorf("last commit is on %s of %d writes, want on shutdown of 1", last.Reason, last.Writes)
+	}
+	if last.Version != 2 {
+		t.Errorf("last commit is version %d, want 2", last.Version)
+	}
+}
+
+func TestCommitOnInterval(t *testing.T) {
+	c, kv := newTestCommitter(t, 0, 10*time.Millisecond)
+	go c.run()
+	defer c.stop()
+
+	kv.Set([]byte("key"), []byte("value"))
+	records := waitCommits(t, c, 1)
+	if records[0].Reason != CommitOnInterval || records[0].Writes != 1 {
+		t.Errorf("commit is on %s of %d writes, want on interval of 1", records[0].Reason, records[0].Writes)
+	}
+}
+
+func TestCommitOnRequest(t *testing.T) {
+	c, kv := newTestCommitter(t, 0, 0)
+	go c.run()
+
+	kv.Set([]byte("key0"), []byte("value"))
+	kv.Set([]byte("key1"), []byte("value"))
+	record := c.commit(CommitOnRequest)
+	if record.Reason != CommitOnRequest || record.Writes != 2 || record.Version != 1 {
+		t.Errorf("commit is version %d on %s of %d writes, want version 1 on request of 2", record.Version, record.Reason, record.Writes)
+	}
+
+	// Nothing is committed without a trigger
+	kv.Set([]byte("key2"), []byte("value"))
+	time.Sleep(10 * time.Millisecond)
+	if records := c.records(); len(records) != 1 {
+		t.Errorf("%d commits are made, want 1", len(records))
+	}
+
+	last := c.stop()
+	if last.Reason != CommitOnShutdown || last.Writes != 1 {
+		t.Errorf("last commit is on %s of %d writes, want on shutdown of 1", last.Reason, last.Writes)
+	}
+	if records := c.records(); len(records) != 2 {
+		t.Errorf("%d commits are made, want 2", len(records))
+	}
+}
+
+func TestCountingStoreIterator(t *testing.T) {
+	c, kv := newTestCommitter(t, 0, 0)
+
+	kv.Set([]byte("a"), []byte("1"))
+	kv.Set([]byte("b"), []byte("2"))
+	kv.Set([]byte("c"), []byte("3"))
+
+	it := kv.Iterator(nil, nil)
+	defer it.Close()
+
+	// The values are read when they are asked for
+	kv.Set([]byte("b"), []byte("22"))
+	kv.Delete([]byte("c"))
+	kv.Set([]byte("d"), []byte("4"))
+
+	want := []struct {
+		key, value string
+	}{
+		{"a", "1"},
+		{"b", "22"},
+		// The deleted key has no value
+		{"c", ""},
+	}
+	for _, w := range want {
+		if !it.Valid() {
+			t.Fatalf("iterator ends before %q", w.key)
+		}
+		if key := it.Key(); string(key) != w.key {
+			t.Errorf("key is %q, want %q", key, w.key)
+		}
+		if value := it.Value(); string(value) != w.value {
+			t.Errorf("value of %q is %q, want %q", w.key, value, w.value)
+		}
+		it.Next()
+	}
+	if it.Valid() {
+		t.Errorf("iterator has the key %q added after the copy", it.Key())
+	}
+
+	if c.pending() != 6 {
+		t.Errorf("%d writes are pending, want 6", c.pending())
+	}
+}
@@ -0,0 +1,173 @@
+package node
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/likecoin/iscn-poc/record"
+
+	cosmos "github.com/cosmos/cosmos-sdk/types"
+)
+
+// The keys of the ID allocator in its own store of the Cosmos SDK store, so
+// the datastore never sees them.
+const (
+	idPrefix      = "iscn-id/"
+	idNoncePrefix = idPrefix + "nonce/"
+	idOwnerPrefix = idPrefix + "owner/"
+)
+
+// ErrDuplicateID is returned when an ID is already allocated to another
+// registrant.
+var ErrDuplicateID = errors.New("ID is already allocated")
+
+// FormatID formats an ID in base58 as the demos do.
+func FormatID(id []byte) string {
+	return base58.Encode(id)
+}
+
+// ParseID parses an ID formatted by FormatID.
+func ParseID(str string) ([]byte, error) {
+	id := base58.Decode(str)
+	if len(id) != record.KernelIDLength {
+		return nil, fmt.Errorf(
+			"%q is not a base58 ID of %d bytes",
+			str,
+			record.KernelIDLength,
+		)
+	}
+	return id, nil
+}
+
+// moveIDs moves the keys of the ID allocator kept in the datastore store by
+// the earlier versions to the ID store, and returns the number of moved keys.
+func moveIDs(from cosmos.KVStore, to cosmos.KVStore) int {
+	it := cosmos.KVStorePrefixIterator(from, []byte(idPrefix))
+	keys := [][]byte{}
+	for ; it.Valid(); it.Next() {
+		to.Set(it.Key(), it.Value())
+		keys = append(keys, it.Key())
+	}
+	it.Close()
+
+	for _, key := range keys {
+		from.Delete(key)
+	}
+	return len(keys)
+}
+
+// deriveID derives the ID of the registrant and the nonce.
+func deriveID(registrant string, nonce uint64) []byte {
+	h := sha256.New()
+	h.Write([]byte(registrant))
+	h.Write([]byte{0})
+	binary.Write(h, binary.BigEndian, nonce)
+	return h.Sum(nil)
+}
+
+// AllocateID allocates the next ID of the registrant, e.g. a Cosmos address.
+// The ID is the SHA-256 hash of the registrant and its nonce, which is kept
+// in the ID store of the Cosmos SDK store and counted as a write by the commit policy. An ID
+// which is already allocated, e.g. reserved by ReserveID, is skipped for the
+// next nonce.
+func (n *Node) AllocateID(registrant string) ([]byte, error) {
+	if len(registrant) == 0 {
+		return nil, errors.New("registrant is empty")
+	}
+
+	if err := n.acquireStore(); err != nil {
+		return nil, err
+	}
+	defer n.release()
+
+	n.committer.lock.Lock()
+	defer n.committer.lock.Unlock()
+
+	kv := n.cms.GetKVStore(n.idsKey)
+	nonceKey := []byte(idNoncePrefix + registrant)
+
+	nonce := uint64(0)
+	if raw := kv.Get(nonceKey); raw != nil {
+		nonce = binary.BigEndian.Uint64(raw)
+	}
+
+	id := deriveID(registrant, nonce)
+	ownerKey := append([]byte(idOwnerPrefix), id...)
+	for kv.Has(ownerKey) {
+		nonce++
+		id = deriveID(registrant, nonce)
+		ownerKey = append([]byte(idOwnerPrefix), id...)
+	}
+
+	next := make([]byte, 8)
+	binary.BigEndian.PutUint64(next, nonce+1)
+	kv.Set(nonceKey, next)
+	kv.Set(ownerKey, []byte(registrant))
+	n.committer.wrote()
+
+	return id, nil
+}
+
+// ReserveID records the ID of a kernel for the registrant, e.g. an ID which
+// is not allocated by AllocateID. It refuses the ID with ErrDuplicateID if it
+// is allocated to another registrant, and does nothing if it is already
+// allocated to the registrant, e.g. for a new version of a kernel.
+func (n *Node) ReserveID(registrant string, id []byte) error {
+	if len(registrant) == 0 {
+		return errors.New("registrant is empty")
+	}
+	if len(id) != record.KernelIDLength {
+		return fmt.Errorf(
+			"ID should be %d bytes, not %d",
+			record.KernelIDLength,
+			len(id),
+		)
+	}
+
+	if err := n.acquireStore(); err != nil {
+		return err
+	}
+	defer n.release()
+
+	n.committer.lock.Lock()
+	defer n.committer.lock.Unlock()
+
+	kv := n.cms.GetKVStore(n.idsKey)
+	ownerKey := append([]byte(idOwnerPrefix), id...)
+	if owner := kv.Get(ownerKey); owner != nil {
+		if string(owner) == registrant {
+			return nil
+		}
+		return fmt.Errorf(
+			"%s of %q: %w",
+			FormatID(id),
+			string(owner),
+			ErrDuplicateID,
+		)
+	}
+
+	kv.Set(ownerKey, []byte(registrant))
+	n.committer.wrote()
+
+	return nil
+}
+
+// IDOwner returns the registrant of an allocated ID, or an empty registrant
+// if the ID is not allocated.
+func (n *Node) IDOwner(id []byte) (string, error) {
+	if err := n.acquireStore(); err != nil {
+		return "", err
+	}
+	defer n.release()
+
+	n.committer.lock.Lock()
+	defer n.committer.lock.Unlock()
+
+	owner := n.cms.GetKVStore(n.idsKey).Get(
+		append([]byte(idOwnerPrefix), id...),
+	)
+	return string(owner), nil
+}
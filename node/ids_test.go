@@ -0,0 +1,58 @@
+package node
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestAllocateIDSkipsTakenIDs(t *testing.T) {
+	n := newTestNode(t)
+
+	// The first ID of alice is reserved by bob
+	if err := n.ReserveID("bob", deriveID("alice", 0)); err != nil {
+		t.Fatalf("ReserveID() = %s", err)
+	}
+
+	for nonce := uint64(1); nonce <= 2; nonce++ {
+		id, err := n.AllocateID("alice")
+		if err != nil {
+			t.Fatalf("AllocateID() = %s", err)
+		}
+		if want := deriveID("alice", nonce); !bytes.Equal(id, want) {
+			t.Errorf("AllocateID() = %s, want %s of nonce %d", FormatID(id), FormatID(want), nonce)
+		}
+
+		owner, err := n.IDOwner(id)
+		if err != nil {
+			t.Fatalf("IDOwner() = %s", err)
+		}
+		if owner != "alice" {
+			t.Errorf("owner of %s is %q, want alice", FormatID(id), owner)
+		}
+	}
+
+	owner, err := n.IDOwner(deriveID("alice", 0))
+	if err != nil {
+		t.Fatalf("IDOwner() = %s", err)
+	}
+	if owner != "bob" {
+		t.Errorf("owner of the reserved ID is %q, want bob", owner)
+	}
+
+	if owner, err := n.IDOwner(deriveID("alice", 3)); err != nil || len(owner) > 0 {
+		t.Errorf("IDOwner() of a free ID = %q, %v", owner, err)
+	}
+
+	err = n.ReserveID("bob", deriveID("alice", 1))
+	if !errors.Is(err, ErrDuplicateID) {
+		t.Errorf("ReserveID() of an ID of alice = %v, want %s", err, ErrDuplicateID)
+	}
+	if err := n.ReserveID("alice", deriveID("alice", 1)); err != nil {
+		t.Errorf("ReserveID() of an ID of the same registrant = %s", err)
+	}
+
+	if pending := n.committer.pending(); pending != 3 {
+		t.Errorf("%d writes are pending, want 3", pending)
+	}
+}
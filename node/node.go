@@ -0,0 +1,393 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"log"
+	"path/filepath"
+	"sync"
+
+	"github.com/cosmos/cosmos-sdk/store"
+	"github.com/ipfs/go-ipfs/core"
+	"github.com/ipfs/go-ipfs/core/coreapi"
+	"github.com/ipfs/go-ipfs/plugin/loader"
+	"github.com/ipfs/go-ipfs/plugin/plugins/cosmosds"
+	"github.com/likecoin/iscn-poc/record"
+
+	cosmos "github.com/cosmos/cosmos-sdk/types"
+	ds "github.com/ipfs/go-datastore"
+	config "github.com/ipfs/go-ipfs-config"
+	libp2p "github.com/ipfs/go-ipfs/core/node/libp2p"
+	ipld "github.com/ipfs/go-ipld-format"
+	icore "github.com/ipfs/interface-go-ipfs-core"
+	abci "github.com/tendermint/tendermint/abci/types"
+	tlog "github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// The plugins inject the datastore and IPLD codecs globally, so they can only
+// be loaded once per process. The cosmosds plugin also holds one Cosmos SDK
+// store for the whole process, and the loader is closed by the shutdown of
+// the node, so only one node can be started per process.
+var (
+	pluginsLock sync.Mutex
+	plugins     *loader.PluginLoader
+	started     bool
+)
+
+// ErrNodeStarted is returned by Start when a node has already been started in
+// the process, even if it is stopped since.
+var ErrNodeStarted = errors.New("a node has already been started in this process")
+
+// Node is an IPFS node which stores its data in a Cosmos SDK store. Only one
+// node can be started per process.
+type Node struct {
+	settings *Settings
+
+	plugins   *loader.PluginLoader
+	ipfsNode  *core.IpfsNode
+	ipfs      icore.CoreAPI
+	db        dbm.DB
+	cacheDB   dbm.DB
+	cms       cosmos.CommitMultiStore
+	storeKey  cosmos.StoreKey
+	idsKey    cosmos.StoreKey
+	committer *committer
+
+	lock     sync.Mutex
+	inflight sync.WaitGroup
+	stopping bool
+}
+
+// New creates a node with the given settings. The node is not running until
+// Start is called.
+func New(settings *Settings) *Node {
+	return &Node{settings: settings}
+}
+
+func setupPlugins(rootPath string) (*loader.PluginLoader, error) {
+	pluginsLock.Lock()
+	defer pluginsLock.Unlock()
+
+	if plugins != nil {
+		return plugins, nil
+	}
+
+	pl, err := loader.NewPluginLoader(rootPath)
+	if err != nil {
+		log.Printf("error loading plugins: %s", err)
+		return nil, err
+	}
+
+	if err := pl.Initialize(); err != nil {
+		log.Printf("error initializing plugins: %s", err)
+		return nil, err
+	}
+
+	if err := pl.Inject(); err != nil {
+		log.Printf("error initializing plugins: %s", err)
+		return nil, err
+	}
+
+	// The paths of the ISCN blocks are resolved by the record package
+	record.RegisterDecoders(ipld.DefaultBlockDecoder)
+
+	plugins = pl
+	return plugins, nil
+}
+
+// claimProcess marks the process as running a node, it fails if a node has
+// already been started.
+func claimProcess() error {
+	pluginsLock.Lock()
+	defer pluginsLock.Unlock()
+
+	if started {
+		return ErrNodeStarted
+	}
+	started = true
+	return nil
+}
+
+// cosmosDatastoreSpec is the spec of the datastore kept in the Cosmos SDK
+// store.
+func cosmosDatastoreSpec(settings *Settings) map[string]interface{} {
+	return map[string]interface{}{
+		"type":   "measure",
+		"prefix": settings.Datastore.MeasurePrefix,
+		"child": map[string]interface{}{
+			"type":        "cosmosds",
+			"path":        "datastore",
+			"compression": settings.Datastore.Compression,
+		},
+	}
+}
+
+func setupDefaultDatastoreConfig(
+	cfg *config.Config,
+	settings *Settings,
+) *config.Config {
+	switch settings.Datastore.Layout {
+	case LayoutBlocks:
+		blocks := cosmosDatastoreSpec(settings)
+		blocks["mountpoint"] = blocksPrefix.String()
+
+		cfg.Datastore.Spec = map[string]interface{}{
+			"type": "mount",
+			"mounts": []interface{}{
+				blocks,
+				map[string]interface{}{
+					"mountpoint": "/",
+					"type":       "measure",
+					"prefix":     settings.Datastore.LocalMeasurePrefix,
+					"child": map[string]interface{}{
+						"type":        "levelds",
+						"path":        "localds",
+						"compression": "none",
+					},
+				},
+			},
+		}
+	default:
+		cfg.Datastore.Spec = cosmosDatastoreSpec(settings)
+		cfg.Datastore.Spec["mountpoint"] = "/"
+	}
+	return cfg
+}
+
+// chainBlocksPrefix returns the prefix of the blocks in the Cosmos SDK store
+// for the datastore layout. The blocks are under the root of the store when it
+// is mounted on the blockstore prefix.
+func chainBlocksPrefix(layout string) ds.Key {
+	if layout == LayoutBlocks {
+		return ds.NewKey("/")
+	}
+	return blocksPrefix
+}
+
+func repoPath(settings *Settings) (string, error) {
+	rootPath, err := filepath.Abs(settings.IPFS.Path)
+	if err != nil {
+		log.Printf("Cannot parse path: %s", err)
+		return "", err
+	}
+
+	rootPath, err = config.Path(rootPath, "")
+	if err != nil {
+		log.Printf("Cannot set config path: %s", err)
+		return "", err
+	}
+
+	return rootPath, nil
+}
+
+func (n *Node) setupCosmosStore(rootPath string) error {
+	pl, err := n.plugins.GetPlugin("ds-cosmos")
+	if err != nil {
+		log.Printf("Cannot retrieve \"ds-cosmos\" plugin: %s", err)
+		return err
+	}
+
+	cosmosDSPlugin, ok := pl.(*cosmosds.Plugin)
+	if !ok {
+		return errors.New("the plugin is not a \"*cosmosds.Plugin\"")
+	}
+
+	settings := n.settings
+	db, err := cosmos.NewLevelDB(settings.Cosmos.DBName, settings.Cosmos.Path)
+	if err != nil {
+		log.Printf("Failed to create LevelDB: %s", err)
+		return err
+	}
+	n.db = db
+
+	key := cosmos.NewKVStoreKey(settings.Cosmos.StoreKey)
+	idsKey := cosmos.NewKVStoreKey(settings.Cosmos.IDStoreKey)
+	cms := store.NewCommitMultiStore(db)
+	// Keep every version so the past records can be read by Snapshot
+	cms.SetPruning(cosmos.PruneNothing)
+	cms.MountStoreWithDB(key, cosmos.StoreTypeIAVL, db)
+	cms.MountStoreWithDB(idsKey, cosmos.StoreTypeIAVL, db)
+	if err := cms.LoadLatestVersion(); err != nil {
+		log.Printf("Cannot load Cosmos store: %s", err)
+		return err
+	}
+	n.cms = cms
+	n.storeKey = key
+	n.idsKey = idsKey
+	n.committer = newCommitter(cms, settings)
+
+	if moved := moveIDs(cms.GetKVStore(key), cms.GetKVStore(idsKey)); moved > 0 {
+		log.Printf("Moved %d keys of the ID allocator to its own store", moved)
+		n.committer.writes += moved
+	}
+
+	ctx := cosmos.NewContext(cms, abci.Header{}, false, tlog.NewNopLogger())
+	var kv cosmos.KVStore = countingStore{
+		KVStore:   ctx.KVStore(key),
+		committer: n.committer,
+	}
+
+	if settings.Datastore.ISCNBlocksOnly {
+		cacheDB, err := cosmos.NewLevelDB(cacheDBName, rootPath)
+		if err != nil {
+			log.Printf("Failed to create LevelDB: %s", err)
+			return err
+		}
+		n.cacheDB = cacheDB
+
+		kv = routingStore{
+			KVStore: kv,
+			cache:   cacheDB,
+			prefix:  chainBlocksPrefix(settings.Datastore.Layout),
+		}
+	}
+
+	err = cosmosDSPlugin.SetCosmosStore(kv)
+	if err != nil {
+		log.Printf("Cannot setup Cosmos store: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// Start opens (or initializes) the repo and the Cosmos store and starts the
+// IPFS node. It fails with ErrNodeStarted if another node has been started in
+// the process.
+func (n *Node) Start(ctx context.Context) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.stopping {
+		return ErrStopping
+	}
+	if n.ipfs != nil {
+		return errors.New("node is already started")
+	}
+	if err := claimProcess(); err != nil {
+		return err
+	}
+
+	rootPath, err := repoPath(n.settings)
+	if err != nil {
+		return err
+	}
+
+	pl, err := setupPlugins(rootPath)
+	if err != nil {
+		return err
+	}
+	n.plugins = pl
+
+	repo, err := openRepo(rootPath, n.settings)
+	if err != nil {
+		log.Printf("Cannot open repo: %s", err)
+		return err
+	}
+
+	buildCfg := &core.BuildCfg{
+		Online:  true,
+		Routing: libp2p.DHTOption,
+		Repo:    repo,
+	}
+	if n.settings.Offline {
+		log.Println("Running in offline mode")
+		buildCfg.Online = false
+		buildCfg.Routing = libp2p.NilRouterOption
+	}
+
+	ipfsNode, err := core.NewNode(ctx, buildCfg)
+	if err != nil {
+		log.Printf("Cannot create node: %s", err)
+		return err
+	}
+	ipfsNode.IsDaemon = true
+	n.ipfsNode = ipfsNode
+
+	ipfs, err := coreapi.NewCoreAPI(ipfsNode)
+	if err != nil {
+		log.Println("No IPFS repo available on the default path")
+		return err
+	}
+
+	log.Println("Start plugin")
+	err = n.plugins.Start(ipfsNode)
+	if err != nil {
+		log.Printf("Cannot start plugins: %s", err)
+		return err
+	}
+
+	if err := n.setupCosmosStore(rootPath); err != nil {
+		return err
+	}
+	go n.committer.run()
+
+	n.ipfs = ipfs
+	return nil
+}
+
+// Stop shuts the node down like Shutdown, with the shutdown timeout of the
+// settings. A stopped node cannot be started again.
+func (n *Node) Stop() error {
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		n.settings.ShutdownTimeout,
+	)
+	defer cancel()
+
+	return n.Shutdown(ctx)
+}
+
+// API returns the core API of the IPFS node.
+func (n *Node) API() icore.CoreAPI {
+	return trackedAPI{CoreAPI: n.ipfs, node: n}
+}
+
+// DAG returns the DAG service of the IPFS node.
+func (n *Node) DAG() icore.APIDagService {
+	return n.API().Dag()
+}
+
+// Store returns the Cosmos SDK store backing the datastore, or nil if the
+// node is not started. Writes made directly to it are not counted by the
+// commit policy.
+func (n *Node) Store() cosmos.CommitMultiStore {
+	return n.cms
+}
+
+// Commit commits the pending writes of the Cosmos SDK store.
+func (n *Node) Commit() (cosmos.CommitID, error) {
+	if err := n.acquireStore(); err != nil {
+		return cosmos.CommitID{}, err
+	}
+	defer n.release()
+
+	return n.committer.commit(CommitOnRequest).CommitID, nil
+}
+
+// LastCommit returns the version and the app hash of the last commit,
+// including the commits made before the node was started.
+func (n *Node) LastCommit() (cosmos.CommitID, error) {
+	if err := n.acquireStore(); err != nil {
+		return cosmos.CommitID{}, err
+	}
+	defer n.release()
+
+	n.committer.lock.Lock()
+	defer n.committer.lock.Unlock()
+	return n.cms.LastCommitID(), nil
+}
+
+// Commits returns the commits made since the node was started, including the
+// commit on shutdown once the node is stopped.
+func (n *Node) Commits() []CommitRecord {
+	n.lock.Lock()
+	c := n.committer
+	n.lock.Unlock()
+
+	if c == nil {
+		return nil
+	}
+	return c.records()
+}
@@ -0,0 +1,96 @@
+package node
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/store"
+
+	cosmos "github.com/cosmos/cosmos-sdk/types"
+	icore "github.com/ipfs/interface-go-ipfs-core"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// testStore is a Cosmos SDK store mounted like the one of the node, on an
+// in-memory database.
+type testStore struct {
+	cms      cosmos.CommitMultiStore
+	storeKey cosmos.StoreKey
+	idsKey   cosmos.StoreKey
+}
+
+func newTestStore(t *testing.T) testStore {
+	t.Helper()
+
+	db := dbm.NewMemDB()
+	s := testStore{
+		cms:      store.NewCommitMultiStore(db),
+		storeKey: cosmos.NewKVStoreKey("StoreKey"),
+		idsKey:   cosmos.NewKVStoreKey("IDStoreKey"),
+	}
+	s.cms.MountStoreWithDB(s.storeKey, cosmos.StoreTypeIAVL, db)
+	s.cms.MountStoreWithDB(s.idsKey, cosmos.StoreTypeIAVL, db)
+	if err := s.cms.LoadLatestVersion(); err != nil {
+		t.Fatalf("cannot load Cosmos store: %s", err)
+	}
+	return s
+}
+
+// newTestNode creates a node on an in-memory Cosmos SDK store without the
+// IPFS node, so only the methods using the store can be called.
+func newTestNode(t *testing.T) *Node {
+	t.Helper()
+
+	s := newTestStore(t)
+	settings := DefaultSettings()
+	return &Node{
+		settings: settings,
+		// The store accessors only need the node to be marked as started
+		ipfs:      struct{ icore.CoreAPI }{},
+		cms:       s.cms,
+		storeKey:  s.storeKey,
+		idsKey:    s.idsKey,
+		committer: newCommitter(s.cms, settings),
+	}
+}
+
+func TestNotStarted(t *testing.T) {
+	n := New(DefaultSettings())
+	id := deriveID("alice", 0)
+
+	check := func(want error) {
+		t.Helper()
+
+		if _, err := n.Commit(); !errors.Is(err, want) {
+			t.Errorf("Commit() = %v, want %s", err, want)
+		}
+		if _, err := n.LastCommit(); !errors.Is(err, want) {
+			t.Errorf("LastCommit() = %v, want %s", err, want)
+		}
+		if _, err := n.Blocks(); !errors.Is(err, want) {
+			t.Errorf("Blocks() = %v, want %s", err, want)
+		}
+		if _, err := n.Snapshot(1); !errors.Is(err, want) {
+			t.Errorf("Snapshot() = %v, want %s", err, want)
+		}
+		if _, err := n.AllocateID("alice"); !errors.Is(err, want) {
+			t.Errorf("AllocateID() = %v, want %s", err, want)
+		}
+		if err := n.ReserveID("alice", id); !errors.Is(err, want) {
+			t.Errorf("ReserveID() = %v, want %s", err, want)
+		}
+		if _, err := n.IDOwner(id); !errors.Is(err, want) {
+			t.Errorf("IDOwner() = %v, want %s", err, want)
+		}
+		if commits := n.Commits(); len(commits) != 0 {
+			t.Errorf("Commits() = %v, want none", commits)
+		}
+	}
+
+	check(ErrNotStarted)
+
+	if err := n.Stop(); err != nil {
+		t.Fatalf("Stop() = %s", err)
+	}
+	check(ErrStopping)
+}
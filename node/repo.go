@@ -0,0 +1,183 @@
+package node
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/cosmos/cosmos-sdk/store"
+	"github.com/ipfs/go-ipfs/repo"
+	"github.com/ipfs/go-ipfs/repo/fsrepo"
+
+	cosmos "github.com/cosmos/cosmos-sdk/types"
+	config "github.com/ipfs/go-ipfs-config"
+	serialize "github.com/ipfs/go-ipfs-config/serialize"
+)
+
+// datastoreSpecFile is the file where fsrepo keeps the disk spec of the
+// datastore which the repo was created with.
+const datastoreSpecFile = "datastore_spec"
+
+func initRepo(rootPath string, settings *Settings) error {
+	cfg, err := config.Init(ioutil.Discard, settings.IPFS.IdentityKeyBits)
+	if err != nil {
+		log.Printf("Cannot init config: %s", err)
+		return err
+	}
+	cfg = setupDefaultDatastoreConfig(cfg, settings)
+
+	err = fsrepo.Init(rootPath, cfg)
+	if err != nil {
+		log.Printf("Cannot init repo: %s", err)
+		return err
+	}
+
+	db, err := cosmos.NewLevelDB(settings.Cosmos.DBName, settings.Cosmos.Path)
+	if err != nil {
+		log.Printf("Failed to create LevelDB: %s", err)
+		return err
+	}
+	db.Close()
+
+	return nil
+}
+
+// specLayout returns the datastore layout of a datastore spec made by
+// setupDefaultDatastoreConfig.
+func specLayout(spec map[string]interface{}) string {
+	if spec["type"] == "mount" {
+		return LayoutBlocks
+	}
+	return LayoutCosmos
+}
+
+// hasChainBlocks tells whether the Cosmos SDK store has any block stored with
+// the datastore layout.
+func hasChainBlocks(settings *Settings, layout string) (bool, error) {
+	db, err := cosmos.NewLevelDB(settings.Cosmos.DBName, settings.Cosmos.Path)
+	if err != nil {
+		log.Printf("Failed to create LevelDB: %s", err)
+		return false, err
+	}
+	defer db.Close()
+
+	key := cosmos.NewKVStoreKey(settings.Cosmos.StoreKey)
+	cms := store.NewCommitMultiStore(db)
+	cms.MountStoreWithDB(key, cosmos.StoreTypeIAVL, db)
+	if err := cms.LoadLatestVersion(); err != nil {
+		log.Printf("Cannot load Cosmos store: %s", err)
+		return false, err
+	}
+
+	kv := cms.GetKVStore(key)
+	return len(chainBlocks(kv, chainBlocksPrefix(layout))) > 0, nil
+}
+
+// migrateRepo checks the datastore spec of an existing repo against the one
+// from setupDefaultDatastoreConfig and rewrites the config when it has changed.
+// The layouts keep the blocks under different keys of the Cosmos SDK store, so
+// a change of layout is refused once a block is stored.
+func migrateRepo(rootPath string, settings *Settings) error {
+	cfg, err := fsrepo.ConfigAt(rootPath)
+	if err != nil {
+		log.Printf("Cannot read config: %s", err)
+		return err
+	}
+
+	layout := specLayout(cfg.Datastore.Spec)
+	if layout != settings.Datastore.Layout {
+		hasBlocks, err := hasChainBlocks(settings, layout)
+		if err != nil {
+			return err
+		}
+		if hasBlocks {
+			return fmt.Errorf(
+				"cannot change datastore layout from %q to %q, the Cosmos store already has blocks",
+				layout,
+				settings.Datastore.Layout,
+			)
+		}
+	}
+
+	current, err := fsrepo.AnyDatastoreConfig(cfg.Datastore.Spec)
+	if err != nil {
+		log.Printf("Cannot parse datastore spec: %s", err)
+		return err
+	}
+
+	cfg = setupDefaultDatastoreConfig(cfg, settings)
+	expected, err := fsrepo.AnyDatastoreConfig(cfg.Datastore.Spec)
+	if err != nil {
+		log.Printf("Cannot parse datastore spec: %s", err)
+		return err
+	}
+
+	specPath := filepath.Join(rootPath, datastoreSpecFile)
+	onDisk, err := ioutil.ReadFile(specPath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Cannot read datastore spec: %s", err)
+		return err
+	}
+
+	diskSpec := expected.DiskSpec().String()
+	if current.DiskSpec().String() == diskSpec && string(onDisk) == diskSpec {
+		return nil
+	}
+
+	log.Printf("Migrating datastore spec from %s to %s", current.DiskSpec(), diskSpec)
+
+	filename, err := config.Filename(rootPath)
+	if err != nil {
+		log.Printf("Cannot locate config file: %s", err)
+		return err
+	}
+
+	if err := serialize.WriteConfigFile(filename, cfg); err != nil {
+		log.Printf("Cannot write config: %s", err)
+		return err
+	}
+
+	if err := ioutil.WriteFile(specPath, []byte(diskSpec), 0600); err != nil {
+		log.Printf("Cannot write datastore spec: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// Init initializes the IPFS repo and the Cosmos store. It does nothing if the
+// repo is already initialized.
+func Init(settings *Settings) error {
+	rootPath, err := repoPath(settings)
+	if err != nil {
+		return err
+	}
+
+	if _, err := setupPlugins(rootPath); err != nil {
+		return err
+	}
+
+	if fsrepo.IsInitialized(rootPath) {
+		log.Printf("Repo %q is already initialized", rootPath)
+		return nil
+	}
+
+	return initRepo(rootPath, settings)
+}
+
+// openRepo opens the repo at rootPath, initializing it on the first run and
+// migrating its datastore spec on the following runs.
+func openRepo(rootPath string, settings *Settings) (repo.Repo, error) {
+	if !fsrepo.IsInitialized(rootPath) {
+		log.Printf("Initializing repo %q ...", rootPath)
+		if err := initRepo(rootPath, settings); err != nil {
+			return nil, err
+		}
+	} else if err := migrateRepo(rootPath, settings); err != nil {
+		return nil, err
+	}
+
+	return fsrepo.Open(rootPath)
+}
@@ -0,0 +1,95 @@
+package node
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/store"
+	"github.com/likecoin/iscn-poc/record"
+
+	cosmos "github.com/cosmos/cosmos-sdk/types"
+	config "github.com/ipfs/go-ipfs-config"
+	serialize "github.com/ipfs/go-ipfs-config/serialize"
+	dshelp "github.com/ipfs/go-ipfs-ds-help"
+)
+
+// writeTestRepo writes the config of a repo with the datastore of the
+// settings, and stores an ISCN block in the Cosmos store with its layout.
+func writeTestRepo(t *testing.T, settings *Settings) string {
+	t.Helper()
+
+	rootPath, err := repoPath(settings)
+	if err != nil {
+		t.Fatalf("cannot get repo path: %s", err)
+	}
+	if err := os.MkdirAll(rootPath, 0700); err != nil {
+		t.Fatalf("cannot create repo: %s", err)
+	}
+
+	filename, err := config.Filename(rootPath)
+	if err != nil {
+		t.Fatalf("cannot locate config file: %s", err)
+	}
+	cfg := setupDefaultDatastoreConfig(&config.Config{}, settings)
+	if err := serialize.WriteConfigFile(filename, cfg); err != nil {
+		t.Fatalf("cannot write config: %s", err)
+	}
+
+	entity, err := (&record.Entity{ID: "lcc://id/demo"}).Encode()
+	if err != nil {
+		t.Fatalf("cannot encode entity: %s", err)
+	}
+
+	db, err := cosmos.NewLevelDB(settings.Cosmos.DBName, settings.Cosmos.Path)
+	if err != nil {
+		t.Fatalf("cannot create LevelDB: %s", err)
+	}
+	defer db.Close()
+
+	key := cosmos.NewKVStoreKey(settings.Cosmos.StoreKey)
+	cms := store.NewCommitMultiStore(db)
+	cms.MountStoreWithDB(key, cosmos.StoreTypeIAVL, db)
+	if err := cms.LoadLatestVersion(); err != nil {
+		t.Fatalf("cannot load Cosmos store: %s", err)
+	}
+
+	prefix := chainBlocksPrefix(settings.Datastore.Layout)
+	cms.GetKVStore(key).Set(
+		prefix.Child(dshelp.CidToDsKey(entity.Cid())).Bytes(),
+		entity.RawData(),
+	)
+	cms.Commit()
+
+	return rootPath
+}
+
+func TestMigrateRepoRefusesLayoutChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "iscn-node")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	settings := DefaultSettings()
+	settings.IPFS.Path = filepath.Join(dir, "ipfs")
+	settings.Cosmos.Path = filepath.Join(dir, "cosmos")
+	rootPath := writeTestRepo(t, settings)
+
+	hasBlocks, err := hasChainBlocks(settings, LayoutCosmos)
+	if err != nil || !hasBlocks {
+		t.Errorf("hasChainBlocks() of %q = %t, %v, want true", LayoutCosmos, hasBlocks, err)
+	}
+	hasBlocks, err = hasChainBlocks(settings, LayoutBlocks)
+	if err != nil || hasBlocks {
+		t.Errorf("hasChainBlocks() of %q = %t, %v, want false", LayoutBlocks, hasBlocks, err)
+	}
+
+	settings.Datastore.Layout = LayoutBlocks
+	err = migrateRepo(rootPath, settings)
+	if err == nil || !strings.Contains(err.Error(), "cannot change datastore layout") {
+		t.Errorf("migrateRepo() = %v, want the layout change refused", err)
+	}
+}
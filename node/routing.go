@@ -0,0 +1,167 @@
+package node
+
+import (
+	"bytes"
+
+	"github.com/likecoin/iscn-poc/record"
+
+	cosmos "github.com/cosmos/cosmos-sdk/types"
+	ds "github.com/ipfs/go-datastore"
+	dshelp "github.com/ipfs/go-ipfs-ds-help"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// cacheDBName is the name of the LevelDB in the IPFS repo which keeps the
+// blocks not belonging to the chain state.
+const cacheDBName = "blockcache"
+
+// routingStore is the KV store handed to the cosmosds plugin when only the
+// ISCN blocks are allowed in the chain state. The other blocks, e.g. unixfs
+// and dag-pb blocks sent by the peers, are routed to a local cache.
+type routingStore struct {
+	// KVStore is the chain store
+	cosmos.KVStore
+
+	cache dbm.DB
+
+	// prefix is the prefix of the blocks in the chain store
+	prefix ds.Key
+}
+
+// isCached tells whether the key is a block which does not belong to the
+// chain state.
+func (s routingStore) isCached(key []byte) bool {
+	k := ds.RawKey(string(key))
+	if !s.prefix.Equal(k.Parent()) {
+		return false
+	}
+
+	c, err := dshelp.DsKeyToCid(ds.NewKey(k.BaseNamespace()))
+	if err != nil {
+		return false
+	}
+
+	return !record.IsISCN(c)
+}
+
+func (s routingStore) Get(key []byte) []byte {
+	if s.isCached(key) {
+		if val := s.cache.Get(key); val != nil {
+			return val
+		}
+		// The block may be written before the routing is enabled
+	}
+	return s.KVStore.Get(key)
+}
+
+func (s routingStore) Has(key []byte) bool {
+	if s.isCached(key) && s.cache.Has(key) {
+		return true
+	}
+	return s.KVStore.Has(key)
+}
+
+func (s routingStore) Set(key, value []byte) {
+	if s.isCached(key) {
+		s.cache.Set(key, value)
+		return
+	}
+	s.KVStore.Set(key, value)
+}
+
+func (s routingStore) Delete(key []byte) {
+	if s.isCached(key) {
+		s.cache.Delete(key)
+		if !s.KVStore.Has(key) {
+			return
+		}
+	}
+	s.KVStore.Delete(key)
+}
+
+func (s routingStore) Iterator(start, end []byte) cosmos.Iterator {
+	return newMergedIterator(
+		s.KVStore.Iterator(start, end),
+		s.cache.Iterator(start, end),
+		false,
+	)
+}
+
+func (s routingStore) ReverseIterator(start, end []byte) cosmos.Iterator {
+	return newMergedIterator(
+		s.KVStore.ReverseIterator(start, end),
+		s.cache.ReverseIterator(start, end),
+		true,
+	)
+}
+
+// mergedIterator iterates over two sorted iterators as one. The first
+// iterator wins when both have the same key.
+type mergedIterator struct {
+	a, b    dbm.Iterator
+	reverse bool
+}
+
+func newMergedIterator(a, b dbm.Iterator, reverse bool) *mergedIterator {
+	it := &mergedIterator{a: a, b: b, reverse: reverse}
+	it.skipDuplicate()
+	return it
+}
+
+// useA tells whether the current item comes from the first iterator.
+func (it *mergedIterator) useA() bool {
+	if !it.b.Valid() {
+		return true
+	}
+	if !it.a.Valid() {
+		return false
+	}
+
+	cmp := bytes.Compare(it.a.Key(), it.b.Key())
+	if it.reverse {
+		return cmp >= 0
+	}
+	return cmp <= 0
+}
+
+func (it *mergedIterator) skipDuplicate() {
+	if it.a.Valid() && it.b.Valid() && bytes.Equal(it.a.Key(), it.b.Key()) {
+		it.b.Next()
+	}
+}
+
+func (it *mergedIterator) Domain() ([]byte, []byte) {
+	return it.a.Domain()
+}
+
+func (it *mergedIterator) Valid() bool {
+	return it.a.Valid() || it.b.Valid()
+}
+
+func (it *mergedIterator) Next() {
+	if it.useA() {
+		it.a.Next()
+	} else {
+		it.b.Next()
+	}
+	it.skipDuplicate()
+}
+
+func (it *mergedIterator) Key() []byte {
+	if it.useA() {
+		return it.a.Key()
+	}
+	return it.b.Key()
+}
+
+func (it *mergedIterator) Value() []byte {
+	if it.useA() {
+		return it.a.Value()
+	}
+	return it.b.Value()
+}
+
+func (it *mergedIterator) Close() {
+	it.a.Close()
+	it.b.Close()
+}
@@ -0,0 +1,119 @@
+package node
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/likecoin/iscn-poc/record"
+
+	dshelp "github.com/ipfs/go-ipfs-ds-help"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// collect walks the iterator and closes it.
+func collect(it dbm.Iterator) (keys []string, values []string) {
+	defer it.Close()
+
+	for ; it.Valid(); it.Next() {
+		keys = append(keys, string(it.Key()))
+		values = append(values, string(it.Value()))
+	}
+	return keys, values
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMergedIterator(t *testing.T) {
+	a := dbm.NewMemDB()
+	b := dbm.NewMemDB()
+	for _, key := range []string{"a", "c", "e"} {
+		a.Set([]byte(key), []byte("a"))
+	}
+	for _, key := range []string{"b", "c", "d", "f"} {
+		b.Set([]byte(key), []byte("b"))
+	}
+
+	keys, values := collect(newMergedIterator(a.Iterator(nil, nil), b.Iterator(nil, nil), false))
+	if want := []string{"a", "b", "c", "d", "e", "f"}; !equalStrings(keys, want) {
+		t.Errorf("keys are %v, want %v", keys, want)
+	}
+	// The first iterator wins for the key in both
+	if want := []string{"a", "b", "a", "b", "a", "b"}; !equalStrings(values, want) {
+		t.Errorf("values are %v, want %v", values, want)
+	}
+
+	keys, values = collect(newMergedIterator(a.ReverseIterator(nil, nil), b.ReverseIterator(nil, nil), true))
+	if want := []string{"f", "e", "d", "c", "b", "a"}; !equalStrings(keys, want) {
+		t.Errorf("reverse keys are %v, want %v", keys, want)
+	}
+	if want := []string{"b", "a", "b", "a", "b", "a"}; !equalStrings(values, want) {
+		t.Errorf("reverse values are %v, want %v", values, want)
+	}
+}
+
+func TestRoutingStore(t *testing.T) {
+	s := newTestStore(t)
+	chain := s.cms.GetKVStore(s.storeKey)
+	cache := dbm.NewMemDB()
+	kv := routingStore{KVStore: chain, cache: cache, prefix: blocksPrefix}
+
+	entity, err := (&record.Entity{ID: "lcc://id/demo"}).Encode()
+	if err != nil {
+		t.Fatalf("cannot encode entity: %s", err)
+	}
+	other, err := cid.Decode("Qmacpqc7EWQBU9q8cctAj1hdoVXdyMH7Geq7FcpZ8XA5M8")
+	if err != nil {
+		t.Fatalf("cannot decode CID: %s", err)
+	}
+
+	iscnKey := blocksPrefix.Child(dshelp.CidToDsKey(entity.Cid())).Bytes()
+	otherKey := blocksPrefix.Child(dshelp.CidToDsKey(other)).Bytes()
+	localKey := []byte("/local/pins")
+
+	kv.Set(iscnKey, []byte("iscn"))
+	kv.Set(otherKey, []byte("other"))
+	kv.Set(localKey, []byte("local"))
+
+	if !chain.Has(iscnKey) || cache.Has(iscnKey) {
+		t.Error("ISCN block is not kept in the chain store")
+	}
+	if chain.Has(otherKey) || !cache.Has(otherKey) {
+		t.Error("other block is not kept in the cache")
+	}
+	if !chain.Has(localKey) || cache.Has(localKey) {
+		t.Error("key out of the blocks is not kept in the chain store")
+	}
+
+	if value := kv.Get(otherKey); string(value) != "other" {
+		t.Errorf("value of the other block is %q, want %q", value, "other")
+	}
+
+	keys, _ := collect(kv.Iterator(nil, nil))
+	want := []string{string(localKey), string(iscnKey), string(otherKey)}
+	sort.Strings(want)
+	if !equalStrings(keys, want) {
+		t.Errorf("keys are %q, want %q", keys, want)
+	}
+
+	keys, _ = collect(kv.ReverseIterator(nil, nil))
+	sort.Sort(sort.Reverse(sort.StringSlice(want)))
+	if !equalStrings(keys, want) {
+		t.Errorf("reverse keys are %q, want %q", keys, want)
+	}
+
+	kv.Delete(otherKey)
+	if kv.Has(otherKey) {
+		t.Error("other block is not deleted")
+	}
+}
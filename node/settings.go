@@ -0,0 +1,211 @@
+package node
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding the
+// settings, e.g. ISCN_COSMOS_PATH overrides "cosmos.path".
+const EnvPrefix = "ISCN_"
+
+// The mount layouts of the datastore.
+const (
+	// LayoutCosmos keeps the whole datastore in the Cosmos SDK store.
+	LayoutCosmos = "cosmos"
+	// LayoutBlocks keeps only the blocks in the Cosmos SDK store and the rest,
+	// e.g. pins, DHT records and keystore, in a local LevelDB.
+	LayoutBlocks = "blocks"
+)
+
+// Settings holds the deployment settings of the ISCN node.
+type Settings struct {
+	// Offline builds the node without networking, so it neither joins the DHT
+	// nor connects to any peer.
+	Offline bool `yaml:"offline"`
+
+	IPFS struct {
+		Path            string `yaml:"path"`
+		IdentityKeyBits int    `yaml:"identity_key_bits"`
+	} `yaml:"ipfs"`
+
+	Cosmos struct {
+		Path     string `yaml:"path"`
+		DBName   string `yaml:"db_name"`
+		StoreKey string `yaml:"store_key"`
+
+		// IDStoreKey is the key of the store of the ID allocator, apart from
+		// the datastore.
+		IDStoreKey string `yaml:"id_store_key"`
+	} `yaml:"cosmos"`
+
+	Datastore struct {
+		// Layout is one of LayoutCosmos and LayoutBlocks.
+		Layout        string `yaml:"layout"`
+		MeasurePrefix string `yaml:"measure_prefix"`
+		Compression   string `yaml:"compression"`
+
+		// ISCNBlocksOnly keeps only the blocks of the ISCN codecs in the Cosmos
+		// SDK store, the other blocks go to a local cache.
+		ISCNBlocksOnly bool `yaml:"iscn_blocks_only"`
+
+		// LocalMeasurePrefix is the measure prefix of the local datastore of
+		// LayoutBlocks.
+		LocalMeasurePrefix string `yaml:"local_measure_prefix"`
+	} `yaml:"datastore"`
+
+	// ShutdownTimeout bounds the time to shut the node down, it should be
+	// positive.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// Commit is the commit policy of the Cosmos SDK store. The store is always
+	// committed on shutdown and on request, zero disables the other triggers.
+	Commit struct {
+		EveryWrites int           `yaml:"every_writes"`
+		Interval    time.Duration `yaml:"interval"`
+	} `yaml:"commit"`
+}
+
+// DefaultSettings returns the settings used when no settings file is given.
+func DefaultSettings() *Settings {
+	s := &Settings{}
+	s.ShutdownTimeout = 30 * time.Second
+	s.IPFS.Path = "./ipfs"
+	s.IPFS.IdentityKeyBits = 2048
+	s.Cosmos.Path = "./cosmos"
+	s.Cosmos.DBName = "application"
+	s.Cosmos.StoreKey = "StoreKey"
+	s.Cosmos.IDStoreKey = "IDStoreKey"
+	s.Datastore.Layout = LayoutCosmos
+	s.Datastore.MeasurePrefix = "cosmossdk.datastore"
+	s.Datastore.ISCNBlocksOnly = true
+	s.Datastore.LocalMeasurePrefix = "leveldb.datastore"
+	s.Datastore.Compression = "none"
+	return s
+}
+
+// LoadSettings loads the settings from the YAML file at path on top of the
+// default settings and then applies the environment variable overrides. An
+// empty path skips the file.
+func LoadSettings(path string) (*Settings, error) {
+	s := DefaultSettings()
+
+	if len(path) > 0 {
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := yaml.UnmarshalStrict(raw, s); err != nil {
+			return nil, fmt.Errorf("cannot parse settings file %q: %s", path, err)
+		}
+	}
+
+	if err := s.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Settings) validate() error {
+	switch s.Datastore.Layout {
+	case LayoutCosmos, LayoutBlocks:
+	default:
+		return fmt.Errorf(
+			"unknown datastore layout %q, it should be %q or %q",
+			s.Datastore.Layout,
+			LayoutCosmos,
+			LayoutBlocks,
+		)
+	}
+
+	if s.ShutdownTimeout <= 0 {
+		return fmt.Errorf(
+			"shutdown timeout should be positive, not %s",
+			s.ShutdownTimeout,
+		)
+	}
+
+	if s.Cosmos.IDStoreKey == s.Cosmos.StoreKey {
+		return fmt.Errorf(
+			"the ID store key should differ from the store key %q",
+			s.Cosmos.StoreKey,
+		)
+	}
+
+	return nil
+}
+
+func (s *Settings) applyEnv() error {
+	strs := map[string]*string{
+		"IPFS_PATH":                &s.IPFS.Path,
+		"COSMOS_PATH":              &s.Cosmos.Path,
+		"COSMOS_DB_NAME":           &s.Cosmos.DBName,
+		"COSMOS_STORE_KEY":         &s.Cosmos.StoreKey,
+		"COSMOS_ID_STORE_KEY":      &s.Cosmos.IDStoreKey,
+		"DATASTORE_LAYOUT":         &s.Datastore.Layout,
+		"DATASTORE_MEASURE_PREFIX": &s.Datastore.MeasurePrefix,
+		"DATASTORE_COMPRESSION":    &s.Datastore.Compression,
+
+		"DATASTORE_LOCAL_MEASURE_PREFIX": &s.Datastore.LocalMeasurePrefix,
+	}
+	for name, field := range strs {
+		if val, ok := os.LookupEnv(EnvPrefix + name); ok {
+			*field = val
+		}
+	}
+
+	ints := map[string]*int{
+		"IPFS_IDENTITY_KEY_BITS": &s.IPFS.IdentityKeyBits,
+		"COMMIT_EVERY_WRITES":    &s.Commit.EveryWrites,
+	}
+	for name, field := range ints {
+		if val, ok := os.LookupEnv(EnvPrefix + name); ok {
+			i, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("%s%s: %s", EnvPrefix, name, err)
+			}
+			*field = i
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"SHUTDOWN_TIMEOUT": &s.ShutdownTimeout,
+		"COMMIT_INTERVAL":  &s.Commit.Interval,
+	}
+	for name, field := range durations {
+		if val, ok := os.LookupEnv(EnvPrefix + name); ok {
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return fmt.Errorf("%s%s: %s", EnvPrefix, name, err)
+			}
+			*field = d
+		}
+	}
+
+	bools := map[string]*bool{
+		"OFFLINE":                    &s.Offline,
+		"DATASTORE_ISCN_BLOCKS_ONLY": &s.Datastore.ISCNBlocksOnly,
+	}
+	for name, field := range bools {
+		if val, ok := os.LookupEnv(EnvPrefix + name); ok {
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return fmt.Errorf("%s%s: %s", EnvPrefix, name, err)
+			}
+			*field = b
+		}
+	}
+
+	return nil
+}
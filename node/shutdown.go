@@ -0,0 +1,215 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	ipld "github.com/ipfs/go-ipld-format"
+	icore "github.com/ipfs/interface-go-ipfs-core"
+)
+
+// ErrStopping is returned for the requests made after the node has begun to
+// shut down.
+var ErrStopping = errors.New("node is stopping")
+
+// ErrNotStarted is returned for the requests to the Cosmos SDK store made
+// before the node is started.
+var ErrNotStarted = errors.New("node is not started")
+
+// acquire registers an in-flight request, it fails once the node is stopping.
+func (n *Node) acquire() error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.stopping {
+		return ErrStopping
+	}
+	n.inflight.Add(1)
+	return nil
+}
+
+// acquireStore registers an in-flight access to the Cosmos SDK store, it
+// fails with ErrNotStarted until the node is started, e.g. after a failed
+// Start, and with ErrStopping once the node is stopping.
+func (n *Node) acquireStore() error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.stopping {
+		return ErrStopping
+	}
+	if n.ipfs == nil {
+		return ErrNotStarted
+	}
+	n.inflight.Add(1)
+	return nil
+}
+
+func (n *Node) release() {
+	n.inflight.Done()
+}
+
+// trackedAPI is the core API handed out by the node, its DAG service is
+// tracked so the in-flight writes are drained on shutdown.
+type trackedAPI struct {
+	icore.CoreAPI
+	node *Node
+}
+
+func (api trackedAPI) Dag() icore.APIDagService {
+	return trackedDAG{
+		APIDagService: api.CoreAPI.Dag(),
+		node:          api.node,
+	}
+}
+
+type trackedDAG struct {
+	icore.APIDagService
+	node *Node
+}
+
+func (dag trackedDAG) Add(ctx context.Context, nd ipld.Node) error {
+	if err := dag.node.acquire(); err != nil {
+		return err
+	}
+	defer dag.node.release()
+	return dag.APIDagService.Add(ctx, nd)
+}
+
+func (dag trackedDAG) AddMany(ctx context.Context, nds []ipld.Node) error {
+	if err := dag.node.acquire(); err != nil {
+		return err
+	}
+	defer dag.node.release()
+	return dag.APIDagService.AddMany(ctx, nds)
+}
+
+func (dag trackedDAG) Pinning() ipld.NodeAdder {
+	return trackedAdder{
+		NodeAdder: dag.APIDagService.Pinning(),
+		node:      dag.node,
+	}
+}
+
+type trackedAdder struct {
+	ipld.NodeAdder
+	node *Node
+}
+
+func (adder trackedAdder) Add(ctx context.Context, nd ipld.Node) error {
+	if err := adder.node.acquire(); err != nil {
+		return err
+	}
+	defer adder.node.release()
+	return adder.NodeAdder.Add(ctx, nd)
+}
+
+func (adder trackedAdder) AddMany(ctx context.Context, nds []ipld.Node) error {
+	if err := adder.node.acquire(); err != nil {
+		return err
+	}
+	defer adder.node.release()
+	return adder.NodeAdder.AddMany(ctx, nds)
+}
+
+// errStepTimeout is returned by shutdownStep when the context is done before
+// the step returns.
+var errStepTimeout = errors.New("timeout")
+
+// shutdownStep runs a step of the shutdown sequence, giving up when the
+// context is done. The step keeps running in the background after a timeout.
+func shutdownStep(ctx context.Context, name string, step func() error) error {
+	log.Printf("Shutdown: %s ...", name)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- step()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			log.Printf("Shutdown: cannot %s: %s", name, err)
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		return nil
+	case <-ctx.Done():
+		log.Printf("Shutdown: timeout to %s", name)
+		return fmt.Errorf("%s: %w (%s)", name, errStepTimeout, ctx.Err())
+	}
+}
+
+// Shutdown stops the node in order: it stops accepting requests, drains the
+// in-flight pins, commits the Cosmos SDK store, closes the IPFS node and the
+// plugins and at last closes the databases. A step which fails does not stop
+// the sequence, the returned error reports all the failed steps. A step which
+// times out is still running, so the sequence stops there and the plugins and
+// the databases are left open rather than closed under it.
+func (n *Node) Shutdown(ctx context.Context) error {
+	n.lock.Lock()
+	if n.stopping {
+		n.lock.Unlock()
+		return nil
+	}
+	n.stopping = true
+	n.lock.Unlock()
+
+	errs := []string{}
+	timedOut := false
+	run := func(name string, step func() error) {
+		if timedOut {
+			log.Printf("Shutdown: skip %s", name)
+			errs = append(errs, fmt.Sprintf("%s: skipped", name))
+			return
+		}
+
+		if err := shutdownStep(ctx, name, step); err != nil {
+			errs = append(errs, err.Error())
+			timedOut = errors.Is(err, errStepTimeout)
+		}
+	}
+
+	run("drain in-flight requests", func() error {
+		n.inflight.Wait()
+		return nil
+	})
+
+	if n.ipfs != nil {
+		run("commit store", func() error {
+			n.committer.stop()
+			return nil
+		})
+	}
+
+	if n.ipfsNode != nil {
+		run("close IPFS node", n.ipfsNode.Close)
+	}
+
+	if n.plugins != nil {
+		run("close plugins", n.plugins.Close)
+	}
+
+	if n.cacheDB != nil {
+		run("close block cache", func() error {
+			n.cacheDB.Close()
+			return nil
+		})
+	}
+
+	if n.db != nil {
+		run("close database", func() error {
+			n.db.Close()
+			return nil
+		})
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("shutdown failed: %s", strings.Join(errs, "; "))
+	}
+
+	log.Println("Shutdown: done")
+	return nil
+}
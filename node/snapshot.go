@@ -0,0 +1,93 @@
+package node
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+
+	cosmos "github.com/cosmos/cosmos-sdk/types"
+	blocks "github.com/ipfs/go-block-format"
+	ds "github.com/ipfs/go-datastore"
+	dshelp "github.com/ipfs/go-ipfs-ds-help"
+	ipld "github.com/ipfs/go-ipld-format"
+)
+
+// blocksPrefix is the datastore prefix of the IPFS blockstore.
+var blocksPrefix = ds.NewKey("/blocks")
+
+// Snapshot is a read-only view of the blocks as of a committed version of the
+// Cosmos SDK store. It implements ipld.NodeGetter, so it can be used in place
+// of the DAG service of the node to read the past records.
+type Snapshot struct {
+	// Version of the Cosmos SDK store, which is also the block height when the
+	// store is committed once per block.
+	Version int64
+
+	kv     cosmos.KVStore
+	prefix ds.Key
+}
+
+// Snapshot opens the Cosmos SDK store read-only at the given version.
+func (n *Node) Snapshot(version int64) (*Snapshot, error) {
+	if err := n.acquireStore(); err != nil {
+		return nil, err
+	}
+	defer n.release()
+
+	n.committer.lock.Lock()
+	defer n.committer.lock.Unlock()
+
+	last := n.cms.LastCommitID().Version
+	if version <= 0 || version > last {
+		return nil, fmt.Errorf(
+			"version %d is out of range, the last version is %d",
+			version,
+			last,
+		)
+	}
+
+	cms, err := n.cms.CacheMultiStoreWithVersion(version)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load version %d: %s", version, err)
+	}
+
+	return &Snapshot{
+		Version: version,
+		kv:      cms.GetKVStore(n.storeKey),
+		prefix:  chainBlocksPrefix(n.settings.Datastore.Layout),
+	}, nil
+}
+
+func (s *Snapshot) blockKey(c cid.Cid) ds.Key {
+	return s.prefix.Child(dshelp.CidToDsKey(c))
+}
+
+// Get fetches the block of the CID from the snapshot.
+func (s *Snapshot) Get(ctx context.Context, c cid.Cid) (ipld.Node, error) {
+	raw := s.kv.Get(s.blockKey(c).Bytes())
+	if raw == nil {
+		return nil, ipld.ErrNotFound
+	}
+
+	b, err := blocks.NewBlockWithCid(raw, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return ipld.Decode(b)
+}
+
+// GetMany fetches the blocks of the CIDs from the snapshot.
+func (s *Snapshot) GetMany(
+	ctx context.Context,
+	cids []cid.Cid,
+) <-chan *ipld.NodeOption {
+	out := make(chan *ipld.NodeOption, len(cids))
+	for _, c := range cids {
+		nd, err := s.Get(ctx, c)
+		out <- &ipld.NodeOption{Node: nd, Err: err}
+	}
+	close(out)
+	return out
+}
@@ -0,0 +1,362 @@
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+
+	blocks "github.com/ipfs/go-block-format"
+	ipld "github.com/ipfs/go-ipld-format"
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// The CARv1 format is written by hand: a varint framed DAG-CBOR header of
+// {"roots": [<cid>, ...], "version": 1}, then a varint framed section of the
+// CID and the data of each block.
+
+// carVersion is the version of the CAR format.
+const carVersion = 1
+
+// maxCARSection bounds the size of a section read from a CAR.
+const maxCARSection = 32 << 20
+
+// The CBOR bytes of the CAR header.
+const (
+	cborMajorBytes = 0x40
+	cborMajorText  = 0x60
+	cborMajorArray = 0x80
+	cborMajorMap   = 0xa0
+	cborTagCid     = 42
+)
+
+// cborHead appends the head of a CBOR item of the major type and argument.
+func cborHead(buf []byte, major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return append(buf, major|byte(arg))
+	case arg <= 0xff:
+		return append(buf, major|24, byte(arg))
+	case arg <= 0xffff:
+		return append(buf, major|25, byte(arg>>8), byte(arg))
+	case arg <= 0xffffffff:
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, uint32(arg))
+		return append(append(buf, major|26), b...)
+	default:
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, arg)
+		return append(append(buf, major|27), b...)
+	}
+}
+
+func cborText(buf []byte, text string) []byte {
+	return append(cborHead(buf, cborMajorText, uint64(len(text))), text...)
+}
+
+// encodeCARHeader encodes the header in DAG-CBOR, the keys are in the
+// canonical order.
+func encodeCARHeader(roots []cid.Cid) []byte {
+	buf := cborHead(nil, cborMajorMap, 2)
+
+	buf = cborText(buf, "roots")
+	buf = cborHead(buf, cborMajorArray, uint64(len(roots)))
+	for _, root := range roots {
+		// A CID is a tag 42 of its bytes with a multibase identity prefix
+		raw := append([]byte{0}, root.Bytes()...)
+		buf = append(buf, 0xc0|24, cborTagCid)
+		buf = cborHead(buf, cborMajorBytes, uint64(len(raw)))
+		buf = append(buf, raw...)
+	}
+
+	buf = cborText(buf, "version")
+	return cborHead(buf, 0, carVersion)
+}
+
+// cborReader reads the subset of CBOR used by the CAR header.
+type cborReader struct {
+	*bytes.Reader
+}
+
+func (r cborReader) head() (byte, uint64, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	major, info := b&0xe0, b&0x1f
+	size := 0
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, fmt.Errorf("unsupported CBOR item 0x%x", b)
+	}
+
+	arg := uint64(0)
+	for i := 0; i < size; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, 0, err
+		}
+		arg = arg<<8 | uint64(b)
+	}
+	return major, arg, nil
+}
+
+func (r cborReader) expect(major byte) (uint64, error) {
+	m, arg, err := r.head()
+	if err != nil {
+		return 0, err
+	}
+	if m != major {
+		return 0, fmt.Errorf("unexpected CBOR major type 0x%x", m)
+	}
+	return arg, nil
+}
+
+func (r cborReader) bytes(major byte) ([]byte, error) {
+	n, err := r.expect(major)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	buf := make([]byte, n)
+	_, err = io.ReadFull(r, buf)
+	return buf, err
+}
+
+func (r cborReader) cid() (cid.Cid, error) {
+	tag, err := r.expect(0xc0)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if tag != cborTagCid {
+		return cid.Undef, fmt.Errorf("unexpected CBOR tag %d", tag)
+	}
+
+	raw, err := r.bytes(cborMajorBytes)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if len(raw) == 0 || raw[0] != 0 {
+		return cid.Undef, errors.New("CID should have a multibase identity prefix")
+	}
+	return cid.Cast(raw[1:])
+}
+
+// decodeCARHeader decodes the roots of the header.
+func decodeCARHeader(raw []byte) ([]cid.Cid, error) {
+	r := cborReader{bytes.NewReader(raw)}
+
+	n, err := r.expect(cborMajorMap)
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []cid.Cid
+	version := uint64(0)
+	for i := uint64(0); i < n; i++ {
+		key, err := r.bytes(cborMajorText)
+		if err != nil {
+			return nil, err
+		}
+
+		switch string(key) {
+		case "roots":
+			count, err := r.expect(cborMajorArray)
+			if err != nil {
+				return nil, err
+			}
+			for j := uint64(0); j < count; j++ {
+				root, err := r.cid()
+				if err != nil {
+					return nil, fmt.Errorf("root %d: %s", j, err)
+				}
+				roots = append(roots, root)
+			}
+		case "version":
+			if version, err = r.expect(0); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown header key %q", key)
+		}
+	}
+
+	if version != carVersion {
+		return nil, fmt.Errorf("CAR version %d is not supported", version)
+	}
+	if len(roots) == 0 {
+		return nil, errors.New("CAR has no root")
+	}
+	return roots, nil
+}
+
+func writeSection(w io.Writer, parts ...[]byte) error {
+	size := 0
+	for _, part := range parts {
+		size += len(part)
+	}
+
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, uint64(size))
+	if _, err := w.Write(buf[:n]); err != nil {
+		return err
+	}
+	for _, part := range parts {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSection(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxCARSection {
+		return nil, fmt.Errorf("section of %d bytes is too large", size)
+	}
+
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// blockLinks lists the links of an ISCN block to export. The footprints,
+// which link to other registrations, and the links to non-ISCN blocks, e.g.
+// the terms, are not followed.
+func blockLinks(nd ipld.Node) ([]cid.Cid, error) {
+	c := nd.Cid()
+	obj, err := iscn.Decode(nd.RawData(), c)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode block %s: %s", c, err)
+	}
+	data, err := blockData(obj)
+	if err != nil {
+		return nil, fmt.Errorf("block %s: %s", c, err)
+	}
+	return links(data, nil), nil
+}
+
+// ExportCAR writes the registration of the kernel as a CARv1 whose root is
+// the kernel. It has the kernel, the rights, the stakeholders, the content
+// with its parent chain and the entities. The footprints and the non-ISCN
+// blocks, e.g. the terms, are not exported. The exported CIDs are returned in
+// the order written.
+func ExportCAR(
+	ctx context.Context,
+	getter ipld.NodeGetter,
+	kernel cid.Cid,
+	w io.Writer,
+) ([]cid.Cid, error) {
+	if kernel.Type() != iscn.CodecISCN {
+		return nil, &PathError{
+			Err:    ErrInvalidCodec,
+			Detail: fmt.Sprintf("block %s is not an ISCN kernel", kernel),
+		}
+	}
+
+	if err := writeSection(w, encodeCARHeader([]cid.Cid{kernel})); err != nil {
+		return nil, err
+	}
+
+	written := []cid.Cid{}
+	seen := map[cid.Cid]bool{kernel: true}
+	queue := []cid.Cid{kernel}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+
+		nd, err := getter.Get(ctx, c)
+		if err != nil {
+			return nil, fmt.Errorf("cannot fetch block %s: %s", c, err)
+		}
+		linked, err := blockLinks(nd)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := writeSection(w, c.Bytes(), nd.RawData()); err != nil {
+			return nil, err
+		}
+		written = append(written, c)
+
+		for _, link := range linked {
+			if !seen[link] {
+				seen[link] = true
+				queue = append(queue, link)
+			}
+		}
+	}
+
+	return written, nil
+}
+
+// ReadCAR reads a CARv1 and verifies the CID of every block against its
+// data.
+func ReadCAR(r io.Reader) ([]cid.Cid, []blocks.Block, error) {
+	br := bufio.NewReader(r)
+
+	header, err := readSection(br)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read CAR header: %s", err)
+	}
+	roots, err := decodeCARHeader(header)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CAR header: %s", err)
+	}
+
+	blks := []blocks.Block{}
+	for {
+		section, err := readSection(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot read block %d: %s", len(blks), err)
+		}
+
+		n, c, err := cid.CidFromBytes(section)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot read CID of block %d: %s", len(blks), err)
+		}
+		data := section[n:]
+
+		sum, err := c.Prefix().Sum(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot hash block %s: %s", c, err)
+		}
+		if !sum.Equals(c) {
+			return nil, nil, fmt.Errorf("block %s does not match its data", c)
+		}
+
+		b, err := blocks.NewBlockWithCid(data, c)
+		if err != nil {
+			return nil, nil, err
+		}
+		blks = append(blks, b)
+	}
+
+	return roots, blks, nil
+}
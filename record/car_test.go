@@ -0,0 +1,96 @@
+package record
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+func TestCARRoundTrip(t *testing.T) {
+	objs := demoBlocks(t)
+
+	buf := bytes.Buffer{}
+	exported, err := ExportCAR(context.Background(), newMemGetter(objs), objs[0].Cid(), &buf)
+	if err != nil {
+		t.Fatalf("ExportCAR() = %s", err)
+	}
+	if len(exported) != len(objs) {
+		t.Errorf("ExportCAR() exports %d blocks, want %d", len(exported), len(objs))
+	}
+
+	roots, blks, err := ReadCAR(&buf)
+	if err != nil {
+		t.Fatalf("ReadCAR() = %s", err)
+	}
+	if len(roots) != 1 || !roots[0].Equals(objs[0].Cid()) {
+		t.Errorf("ReadCAR() roots = %v, want [%s]", roots, objs[0].Cid())
+	}
+
+	read := map[cid.Cid][]byte{}
+	for _, b := range blks {
+		read[b.Cid()] = b.RawData()
+	}
+	for _, obj := range objs {
+		data, ok := read[obj.Cid()]
+		if !ok {
+			t.Errorf("%s %s is not in the CAR", obj.GetName(), obj.Cid())
+			continue
+		}
+		if !bytes.Equal(data, obj.RawData()) {
+			t.Errorf("%s %s is read with other data", obj.GetName(), obj.Cid())
+		}
+	}
+}
+
+func TestReadCARVersion(t *testing.T) {
+	root := demoBlocks(t)[0].Cid()
+
+	// The header of encodeCARHeader with version 2
+	header := encodeCARHeader([]cid.Cid{root})
+	header[len(header)-1] = 2
+
+	buf := bytes.Buffer{}
+	if err := writeSection(&buf, header); err != nil {
+		t.Fatalf("cannot write header: %s", err)
+	}
+
+	_, _, err := ReadCAR(&buf)
+	if err == nil || !strings.Contains(err.Error(), "version 2 is not supported") {
+		t.Errorf("ReadCAR() = %v, want the version not supported", err)
+	}
+}
+
+func TestReadCARTruncated(t *testing.T) {
+	objs := demoBlocks(t)
+
+	buf := bytes.Buffer{}
+	if _, err := ExportCAR(context.Background(), newMemGetter(objs), objs[0].Cid(), &buf); err != nil {
+		t.Fatalf("ExportCAR() = %s", err)
+	}
+
+	_, _, err := ReadCAR(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	if err == nil {
+		t.Error("ReadCAR() of a truncated CAR succeeds")
+	}
+}
+
+func TestReadCARMismatch(t *testing.T) {
+	objs := demoBlocks(t)
+	kernel, entity := objs[0], objs[4]
+
+	buf := bytes.Buffer{}
+	if err := writeSection(&buf, encodeCARHeader([]cid.Cid{kernel.Cid()})); err != nil {
+		t.Fatalf("cannot write header: %s", err)
+	}
+	if err := writeSection(&buf, kernel.Cid().Bytes(), entity.RawData()); err != nil {
+		t.Fatalf("cannot write block: %s", err)
+	}
+
+	_, _, err := ReadCAR(&buf)
+	if err == nil || !strings.Contains(err.Error(), "does not match its data") {
+		t.Errorf("ReadCAR() = %v, want the block not matching its data", err)
+	}
+}
@@ -0,0 +1,51 @@
+package record
+
+import (
+	"sort"
+
+	"github.com/ipfs/go-cid"
+
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// codecNames are the names of the ISCN codecs.
+var codecNames = map[uint64]string{
+	iscn.CodecISCN:         "kernel",
+	iscn.CodecContent:      "content",
+	iscn.CodecEntity:       "entity",
+	iscn.CodecRights:       "rights",
+	iscn.CodecStakeholders: "stakeholders",
+}
+
+// IsISCN tells whether the CID is of an ISCN block.
+func IsISCN(c cid.Cid) bool {
+	_, ok := codecNames[c.Type()]
+	return ok
+}
+
+// CodecName returns the name of an ISCN codec, e.g. "kernel". ok is false if
+// the codec is not an ISCN codec.
+func CodecName(codec uint64) (name string, ok bool) {
+	name, ok = codecNames[codec]
+	return name, ok
+}
+
+// CodecByName returns the ISCN codec of the name, e.g. "kernel".
+func CodecByName(name string) (codec uint64, ok bool) {
+	for codec, n := range codecNames {
+		if n == name {
+			return codec, true
+		}
+	}
+	return 0, false
+}
+
+// CodecNames returns the sorted names of the ISCN codecs.
+func CodecNames() []string {
+	names := make([]string, 0, len(codecNames))
+	for _, name := range codecNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,104 @@
+package record
+
+import (
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// Content is a version of the registered content. A new version links to the
+// previous one as its parent.
+type Content struct {
+	Type        string
+	Version     uint64
+	Fingerprint string
+	Title       string
+
+	// Optional properties
+	Parent      cid.Cid
+	Source      string
+	Edition     string
+	Description string
+	Tags        []string
+}
+
+// Encode encodes the content into an ISCN content block.
+func (c *Content) Encode() (iscn.IscnObject, error) {
+	data := map[string]interface{}{
+		"type":        c.Type,
+		"version":     c.Version,
+		"fingerprint": c.Fingerprint,
+		"title":       c.Title,
+	}
+	if c.Parent.Defined() {
+		data["parent"] = c.Parent
+	} else {
+		data["parent"] = nil
+	}
+	if len(c.Source) > 0 {
+		data["source"] = c.Source
+	}
+	if len(c.Edition) > 0 {
+		data["edition"] = c.Edition
+	}
+	if len(c.Description) > 0 {
+		data["description"] = c.Description
+	}
+	if len(c.Tags) > 0 {
+		data["tags"] = c.Tags
+	}
+
+	return iscn.Encode(iscn.CodecContent, SchemaVersion, data)
+}
+
+// FromObject reads the content from an ISCN content block.
+func (c *Content) FromObject(obj iscn.IscnObject) error {
+	return c.fromObject(obj).first()
+}
+
+// fromObject reads the content, the properties which cannot be read are left
+// zero.
+func (c *Content) fromObject(obj iscn.IscnObject) decodeErrors {
+	errs := decodeErrors{}
+	if err := checkCodec(obj, iscn.CodecContent); err != nil {
+		return append(errs, err)
+	}
+
+	var err error
+	c.Type, err = GetString(obj, "type")
+	errs.add(err)
+	c.Version, err = GetUint64(obj, "version")
+	errs.add(err)
+	c.Fingerprint, err = GetString(obj, "fingerprint")
+	errs.add(err)
+	c.Title, err = GetString(obj, "title")
+	errs.add(err)
+	c.Parent, err = getCid(obj, "parent")
+	errs.add(err)
+	c.Source, err = getString(obj, "source")
+	errs.add(err)
+	c.Edition, err = getString(obj, "edition")
+	errs.add(err)
+	c.Description, err = getString(obj, "description")
+	errs.add(err)
+
+	c.Tags = nil
+	tags, err := GetArray(obj, "tags")
+	errs.add(optional(err))
+	for i, tag := range tags {
+		t, ok := tag.(string)
+		if !ok {
+			errs.add(&PathError{
+				Path:   fmt.Sprintf("tags/%d", i),
+				Err:    ErrTypeMismatch,
+				Detail: "tag is not a string",
+			})
+			continue
+		}
+		c.Tags = append(c.Tags, t)
+	}
+
+	return errs
+}
@@ -0,0 +1,394 @@
+package record
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+)
+
+// NamespaceSeparator separates the namespace and the name of a custom
+// property of the kernel, e.g. "likecoin:rank".
+const NamespaceSeparator = ":"
+
+// The types of the custom properties.
+const (
+	TypeString = iota
+	TypeInt
+	TypeUint
+	TypeBytes
+	TypeCid
+	TypeStrings
+	TypeObject
+)
+
+var typeNames = map[int]string{
+	TypeString:  "a string",
+	TypeInt:     "an integer",
+	TypeUint:    "an unsigned integer",
+	TypeBytes:   "bytes",
+	TypeCid:     "a CID",
+	TypeStrings: "a list of strings",
+	TypeObject:  "an object",
+}
+
+// Property is the schema of a custom property.
+type Property struct {
+	// Type is one of TypeString, TypeInt, TypeUint, TypeBytes, TypeCid,
+	// TypeStrings and TypeObject.
+	Type     int
+	Required bool
+
+	// Properties are the properties of an object.
+	Properties map[string]Property
+}
+
+var (
+	namespacesLock sync.RWMutex
+
+	// namespaces are the schemas of the registered namespaces
+	namespaces = map[string]map[string]Property{}
+)
+
+// RegisterNamespace registers the schema of the custom properties of the
+// kernel in the namespace, e.g. "publisher". The properties are named without
+// the namespace, and a required property is only required once any property
+// of the namespace is used.
+func RegisterNamespace(namespace string, properties map[string]Property) {
+	namespacesLock.Lock()
+	defer namespacesLock.Unlock()
+
+	namespaces[namespace] = properties
+}
+
+func namespaceOf(key string) (string, map[string]Property, bool) {
+	i := strings.Index(key, NamespaceSeparator)
+	if i < 0 {
+		return "", nil, false
+	}
+	namespace := key[:i]
+
+	namespacesLock.RLock()
+	defer namespacesLock.RUnlock()
+
+	properties, ok := namespaces[namespace]
+	return namespace, properties, ok
+}
+
+// Properties are the custom properties of the kernel.
+type Properties map[string]interface{}
+
+// Unregistered returns the sorted keys which are not in a registered
+// namespace.
+func (p Properties) Unregistered() []string {
+	keys := []string{}
+	for key := range p {
+		if _, _, ok := namespaceOf(key); !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (p Properties) validate() ValidationErrors {
+	errs := ValidationErrors{}
+	used := map[string]map[string]Property{}
+
+	for key, value := range p {
+		namespace, properties, ok := namespaceOf(key)
+		if !ok {
+			continue
+		}
+		used[namespace] = properties
+
+		name := key[len(namespace)+len(NamespaceSeparator):]
+		property, ok := properties[name]
+		if !ok {
+			errs.add(key, "is not declared in namespace %q", namespace)
+			continue
+		}
+		errs.prefix(key, property.validate(value))
+	}
+
+	for namespace, properties := range used {
+		for name, property := range properties {
+			key := namespace + NamespaceSeparator + name
+			if _, ok := p[key]; !ok && property.Required {
+				errs.add(key, "is required")
+			}
+		}
+	}
+
+	return errs
+}
+
+// Validate checks the properties of the registered namespaces against their
+// schemas. The unregistered properties are not checked.
+func (p Properties) Validate() error {
+	return p.validate().err()
+}
+
+// validate checks the value, the paths of the errors are relative to the
+// value.
+func (property *Property) validate(value interface{}) ValidationErrors {
+	errs := ValidationErrors{}
+
+	ok := false
+	switch property.Type {
+	case TypeString:
+		_, ok = value.(string)
+	case TypeInt:
+		_, ok = toInt64(value)
+	case TypeUint:
+		_, ok = toUint64(value)
+	case TypeBytes:
+		_, ok = value.([]byte)
+	case TypeCid:
+		_, ok = value.(cid.Cid)
+	case TypeStrings:
+		_, ok = toStrings(value)
+	case TypeObject:
+		var obj map[string]interface{}
+		if obj, ok = toObject(value); !ok {
+			break
+		}
+
+		for name, v := range obj {
+			nested, declared := property.Properties[name]
+			if !declared {
+				errs.add(name, "is not declared")
+				continue
+			}
+			errs.prefix(name, nested.validate(v))
+		}
+		for name, nested := range property.Properties {
+			if _, found := obj[name]; !found && nested.Required {
+				errs.add(name, "is required")
+			}
+		}
+	default:
+		errs.add("", "has an unknown type %d", property.Type)
+		return errs
+	}
+
+	if !ok {
+		errs.add("", "should be %s", typeNames[property.Type])
+	}
+	return errs
+}
+
+func toInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint:
+		if uint64(v) <= 1<<63-1 {
+			return int64(v), true
+		}
+	case uint64:
+		if v <= 1<<63-1 {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
+
+func toUint64(value interface{}) (uint64, bool) {
+	switch v := value.(type) {
+	case uint:
+		return uint64(v), true
+	case uint8:
+		return uint64(v), true
+	case uint16:
+		return uint64(v), true
+	case uint32:
+		return uint64(v), true
+	case uint64:
+		return v, true
+	}
+	if i, ok := toInt64(value); ok && i >= 0 {
+		return uint64(i), true
+	}
+	return 0, false
+}
+
+func toStrings(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		strs := make([]string, 0, len(v))
+		for _, e := range v {
+			str, ok := e.(string)
+			if !ok {
+				return nil, false
+			}
+			strs = append(strs, str)
+		}
+		return strs, true
+	}
+	return nil, false
+}
+
+func toObject(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case Properties:
+		return v, true
+	case map[interface{}]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			str, ok := key.(string)
+			if !ok {
+				return nil, false
+			}
+			obj[str] = val
+		}
+		return obj, true
+	}
+	return nil, false
+}
+
+// lookup finds the value of the dotted path, e.g. "p.b.bb". The errors of the
+// getters are *PathError with the dotted path.
+func (p Properties) lookup(path string) (interface{}, error) {
+	names := strings.Split(path, ".")
+
+	var value interface{} = map[string]interface{}(p)
+	for i, name := range names {
+		obj, ok := toObject(value)
+		if !ok {
+			return nil, &PathError{
+				Path:   strings.Join(names[:i], "."),
+				Err:    ErrTypeMismatch,
+				Detail: "should be an object",
+			}
+		}
+		if value, ok = obj[name]; !ok {
+			return nil, &PathError{Path: path, Err: ErrNotFound}
+		}
+	}
+	return value, nil
+}
+
+// Lookup reads the value of the dotted path, ok is false if it is missing.
+func (p Properties) Lookup(path string) (val interface{}, ok bool) {
+	val, err := p.lookup(path)
+	return val, err == nil
+}
+
+func mismatchError(path string, typ int) error {
+	return &PathError{
+		Path:   path,
+		Err:    ErrTypeMismatch,
+		Detail: "should be " + typeNames[typ],
+	}
+}
+
+// GetString reads the string of the dotted path, e.g. "p.b.ba".
+func (p Properties) GetString(path string) (string, error) {
+	value, err := p.lookup(path)
+	if err != nil {
+		return "", err
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", mismatchError(path, TypeString)
+	}
+	return str, nil
+}
+
+// GetInt reads the integer of the dotted path, e.g. "p.b.bb".
+func (p Properties) GetInt(path string) (int64, error) {
+	value, err := p.lookup(path)
+	if err != nil {
+		return 0, err
+	}
+	i, ok := toInt64(value)
+	if !ok {
+		return 0, mismatchError(path, TypeInt)
+	}
+	return i, nil
+}
+
+// GetUint reads the unsigned integer of the dotted path.
+func (p Properties) GetUint(path string) (uint64, error) {
+	value, err := p.lookup(path)
+	if err != nil {
+		return 0, err
+	}
+	u, ok := toUint64(value)
+	if !ok {
+		return 0, mismatchError(path, TypeUint)
+	}
+	return u, nil
+}
+
+// GetBytes reads the bytes of the dotted path.
+func (p Properties) GetBytes(path string) ([]byte, error) {
+	value, err := p.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil, mismatchError(path, TypeBytes)
+	}
+	return b, nil
+}
+
+// GetCid reads the CID of the dotted path.
+func (p Properties) GetCid(path string) (cid.Cid, error) {
+	value, err := p.lookup(path)
+	if err != nil {
+		return cid.Undef, err
+	}
+	c, ok := value.(cid.Cid)
+	if !ok {
+		return cid.Undef, mismatchError(path, TypeCid)
+	}
+	return c, nil
+}
+
+// GetStrings reads the list of strings of the dotted path.
+func (p Properties) GetStrings(path string) ([]string, error) {
+	value, err := p.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	strs, ok := toStrings(value)
+	if !ok {
+		return nil, mismatchError(path, TypeStrings)
+	}
+	return strs, nil
+}
+
+// GetObject reads the object of the dotted path, e.g. "p.b".
+func (p Properties) GetObject(path string) (Properties, error) {
+	value, err := p.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := toObject(value)
+	if !ok {
+		return nil, mismatchError(path, TypeObject)
+	}
+	return obj, nil
+}
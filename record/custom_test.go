@@ -0,0 +1,108 @@
+package record
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// testNamespace is registered with the custom properties of the demo kernel.
+const testNamespace = "demo"
+
+func init() {
+	RegisterNamespace(testNamespace, map[string]Property{
+		"xxx": {Type: TypeBytes},
+		"p": {
+			Type: TypeObject,
+			Properties: map[string]Property{
+				"a": {Type: TypeInt},
+				"b": {
+					Type: TypeObject,
+					Properties: map[string]Property{
+						"ba": {Type: TypeString},
+						"bb": {Type: TypeInt, Required: true},
+					},
+				},
+			},
+		},
+	})
+}
+
+func demoProperties() Properties {
+	return Properties{
+		"zzz":      -987654321,
+		"yyy":      []string{"abc", "def", "ghi"},
+		"demo:xxx": []byte{'x', 'y', 'z'},
+		"demo:p": map[string]interface{}{
+			"a": 10,
+			"b": map[string]interface{}{
+				"ba": "abc",
+				"bb": 123,
+			},
+		},
+	}
+}
+
+func TestPropertiesGetters(t *testing.T) {
+	p := demoProperties()
+
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Validate() = %s", err)
+	}
+
+	unregistered := p.Unregistered()
+	if want := []string{"yyy", "zzz"}; !reflect.DeepEqual(unregistered, want) {
+		t.Errorf("Unregistered() = %v, want %v", unregistered, want)
+	}
+
+	bb, err := p.GetInt("demo:p.b.bb")
+	if err != nil || bb != 123 {
+		t.Errorf("GetInt(\"demo:p.b.bb\") = %d, %v, want 123", bb, err)
+	}
+
+	ba, err := p.GetString("demo:p.b.ba")
+	if err != nil || ba != "abc" {
+		t.Errorf("GetString(\"demo:p.b.ba\") = %q, %v, want \"abc\"", ba, err)
+	}
+
+	xxx, err := p.GetBytes("demo:xxx")
+	if err != nil || string(xxx) != "xyz" {
+		t.Errorf("GetBytes(\"demo:xxx\") = %q, %v, want \"xyz\"", xxx, err)
+	}
+
+	if _, err := p.GetString("demo:p.b.bb"); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("GetString(\"demo:p.b.bb\") error = %v, want ErrTypeMismatch", err)
+	}
+	if _, err := p.GetInt("demo:p.b.bc"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetInt(\"demo:p.b.bc\") error = %v, want ErrNotFound", err)
+	}
+	if _, err := p.GetInt("demo:p.a.b"); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("GetInt(\"demo:p.a.b\") error = %v, want ErrTypeMismatch", err)
+	}
+}
+
+func TestPropertiesValidate(t *testing.T) {
+	p := Properties{
+		"demo:p": map[string]interface{}{
+			"a": "ten",
+			"b": map[string]interface{}{},
+		},
+		"demo:yyy": "abc",
+	}
+
+	err := p.Validate()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Validate() = %v, want ValidationErrors", err)
+	}
+
+	paths := map[string]bool{}
+	for _, e := range errs {
+		paths[e.Path] = true
+	}
+	for _, path := range []string{"demo:p/a", "demo:p/b/bb", "demo:yyy"} {
+		if !paths[path] {
+			t.Errorf("Validate() = %s, want an error of %q", err, path)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// The kinds of the changes of a diff.
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// Change is a field changed between two blocks.
+type Change struct {
+	// Path of the field, e.g. "rights/1/period/to".
+	Path string `json:"path"`
+
+	// Kind is one of ChangeAdded, ChangeRemoved and ChangeModified.
+	Kind string `json:"kind"`
+
+	// Old and New are null for an added and a removed field, and for a null
+	// value such as the parent of a first content version.
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// Changes are the changes of a diff sorted by path, the array indexes are
+// sorted as numbers.
+type Changes []Change
+
+// Diff compares two blocks of the same codec field by field, including the
+// objects and the arrays nested in them. A link is modified if it targets
+// another block, the linked blocks are not compared.
+func Diff(a, b iscn.IscnObject) (Changes, error) {
+	if a.Cid().Type() != b.Cid().Type() {
+		return nil, &PathError{
+			Err: ErrInvalidCodec,
+			Detail: fmt.Sprintf(
+				"block %s is of codec 0x%x, block %s is of codec 0x%x",
+				a.Cid(),
+				a.Cid().Type(),
+				b.Cid(),
+				b.Cid().Type(),
+			),
+		}
+	}
+
+	dataA, err := blockData(a)
+	if err != nil {
+		return nil, err
+	}
+	dataB, err := blockData(b)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := diffValue("", dataA, dataB, nil)
+	sort.SliceStable(changes, func(i, j int) bool {
+		return pathLess(changes[i].Path, changes[j].Path)
+	})
+	return changes, nil
+}
+
+// pathLess compares two paths segment by segment, the indexes as numbers, so
+// "rights/2" is before "rights/10".
+func pathLess(a, b string) bool {
+	segmentsA := strings.Split(a, "/")
+	segmentsB := strings.Split(b, "/")
+	for i := 0; i < len(segmentsA) && i < len(segmentsB); i++ {
+		segA, segB := segmentsA[i], segmentsB[i]
+		if segA == segB {
+			continue
+		}
+
+		indexA, errA := strconv.Atoi(segA)
+		indexB, errB := strconv.Atoi(segB)
+		if errA == nil && errB == nil {
+			return indexA < indexB
+		}
+		return segA < segB
+	}
+	return len(segmentsA) < len(segmentsB)
+}
+
+// elements returns the elements of an array, ok is false if the value is not
+// an array.
+func elements(value interface{}) ([]interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, true
+	case []map[string]interface{}:
+		elems := make([]interface{}, 0, len(v))
+		for _, e := range v {
+			elems = append(elems, e)
+		}
+		return elems, true
+	case []string:
+		elems := make([]interface{}, 0, len(v))
+		for _, e := range v {
+			elems = append(elems, e)
+		}
+		return elems, true
+	}
+	return nil, false
+}
+
+func equalValue(a, b interface{}) bool {
+	switch v := a.(type) {
+	case cid.Cid:
+		c, ok := b.(cid.Cid)
+		return ok && v.Equals(c)
+	case []byte:
+		b, ok := b.([]byte)
+		return ok && bytes.Equal(v, b)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func diffValue(path string, a, b interface{}, changes Changes) Changes {
+	mapA, okA := a.(map[string]interface{})
+	mapB, okB := b.(map[string]interface{})
+	if okA && okB {
+		keys := map[string]bool{}
+		for key := range mapA {
+			keys[key] = true
+		}
+		for key := range mapB {
+			keys[key] = true
+		}
+
+		for key := range keys {
+			valA, inA := mapA[key]
+			valB, inB := mapB[key]
+			p := joinPath(path, key)
+			switch {
+			case !inA:
+				changes = append(changes, Change{Path: p, Kind: ChangeAdded, New: valB})
+			case !inB:
+				changes = append(changes, Change{Path: p, Kind: ChangeRemoved, Old: valA})
+			default:
+				changes = diffValue(p, valA, valB, changes)
+			}
+		}
+		return changes
+	}
+
+	elemsA, okA := elements(a)
+	elemsB, okB := elements(b)
+	if okA && okB {
+		for i := 0; i < len(elemsA) || i < len(elemsB); i++ {
+			p := joinPath(path, strconv.Itoa(i))
+			switch {
+			case i >= len(elemsA):
+				changes = append(changes, Change{Path: p, Kind: ChangeAdded, New: elemsB[i]})
+			case i >= len(elemsB):
+				changes = append(changes, Change{Path: p, Kind: ChangeRemoved, Old: elemsA[i]})
+			default:
+				changes = diffValue(p, elemsA[i], elemsB[i], changes)
+			}
+		}
+		return changes
+	}
+
+	if !equalValue(a, b) {
+		changes = append(changes, Change{Path: path, Kind: ChangeModified, Old: a, New: b})
+	}
+	return changes
+}
+
+// formatValue formats a value of a change as JSON, the links are formatted
+// as CIDs.
+func formatValue(value interface{}) string {
+	if c, ok := value.(cid.Cid); ok {
+		return c.String()
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(raw)
+}
+
+// String formats the changes one per line, "+" for an added field, "-" for a
+// removed field and "~" for a modified field.
+func (changes Changes) String() string {
+	lines := make([]string, 0, len(changes))
+	for _, change := range changes {
+		switch change.Kind {
+		case ChangeAdded:
+			lines = append(lines, fmt.Sprintf("+ %s: %s", change.Path, formatValue(change.New)))
+		case ChangeRemoved:
+			lines = append(lines, fmt.Sprintf("- %s: %s", change.Path, formatValue(change.Old)))
+		default:
+			lines = append(lines, fmt.Sprintf(
+				"~ %s: %s -> %s",
+				change.Path,
+				formatValue(change.Old),
+				formatValue(change.New),
+			))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,113 @@
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+func encodeContent(t *testing.T, c Content) iscn.IscnObject {
+	t.Helper()
+
+	obj, err := c.Encode()
+	if err != nil {
+		t.Fatalf("cannot encode content: %s", err)
+	}
+	return obj
+}
+
+func TestDiffContentVersions(t *testing.T) {
+	v1 := encodeContent(t, Content{
+		Type:        "article",
+		Version:     1,
+		Fingerprint: "hash://sha256/9564b85669d5e96ac969dd0161b8475bbced9e5999c6ec598da718a3045d6f2e",
+		Title:       "ISCN Demo",
+		Source:      "https://example.com/demo",
+		Edition:     "1st",
+		Description: "The first version",
+		Tags:        []string{"demo", "iscn"},
+	})
+	v2 := encodeContent(t, Content{
+		Type:        "article",
+		Version:     2,
+		Fingerprint: "hash://sha256/9564b85669d5e96ac969dd0161b8475bbced9e5999c6ec598da718a3045d6f2e",
+		Title:       "ISCN Demo",
+		Parent:      v1.Cid(),
+	})
+
+	changes, err := Diff(v1, v2)
+	if err != nil {
+		t.Fatalf("Diff() = %s", err)
+	}
+
+	want := []struct {
+		path string
+		kind string
+	}{
+		{"description", ChangeRemoved},
+		{"edition", ChangeRemoved},
+		{"parent", ChangeModified},
+		{"source", ChangeRemoved},
+		{"tags", ChangeRemoved},
+		{"version", ChangeModified},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("Diff() =\n%s\nwant %d changes", changes, len(want))
+	}
+	for i, w := range want {
+		if changes[i].Path != w.path || changes[i].Kind != w.kind {
+			t.Errorf("change %d is %s %s, want %s %s", i, changes[i].Kind, changes[i].Path, w.kind, w.path)
+		}
+	}
+
+	parent := changes[2]
+	if parent.Old != nil {
+		t.Errorf("old parent is %v, want nil", parent.Old)
+	}
+	if !equalValue(parent.New, v1.Cid()) {
+		t.Errorf("new parent is %v, want %s", parent.New, v1.Cid())
+	}
+
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		t.Fatalf("cannot marshal JSON: %s", err)
+	}
+	if !strings.Contains(string(raw), `{"path":"parent","kind":"modified","old":null,`) {
+		t.Errorf("JSON of the changes does not keep the null old parent:\n%s", raw)
+	}
+	if !strings.Contains(string(raw), `{"path":"tags","kind":"removed","old":["demo","iscn"],"new":null}`) {
+		t.Errorf("JSON of the changes does not keep the null new tags:\n%s", raw)
+	}
+
+	if line := strings.Split(changes.String(), "\n")[4]; line != `- tags: ["demo","iscn"]` {
+		t.Errorf("String() line of the tags is %q", line)
+	}
+}
+
+func TestDiffSortsIndexes(t *testing.T) {
+	tagsA := []string{}
+	tagsB := []string{}
+	for i := 0; i < 11; i++ {
+		tagsA = append(tagsA, fmt.Sprintf("a%d", i))
+		tagsB = append(tagsB, fmt.Sprintf("b%d", i))
+	}
+
+	a := encodeContent(t, Content{Type: "article", Version: 1, Fingerprint: "fp", Title: "A", Tags: tagsA})
+	b := encodeContent(t, Content{Type: "article", Version: 1, Fingerprint: "fp", Title: "A", Tags: tagsB})
+
+	changes, err := Diff(a, b)
+	if err != nil {
+		t.Fatalf("Diff() = %s", err)
+	}
+	if len(changes) != 11 {
+		t.Fatalf("Diff() =\n%s\nwant 11 changes", changes)
+	}
+	for i, change := range changes {
+		if want := fmt.Sprintf("tags/%d", i); change.Path != want {
+			t.Errorf("change %d is of %s, want %s", i, change.Path, want)
+		}
+	}
+}
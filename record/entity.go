@@ -0,0 +1,53 @@
+package record
+
+import (
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// Entity is a person or an organization taking part in a registration.
+type Entity struct {
+	ID string
+
+	// Optional properties
+	Name        string
+	Description string
+}
+
+// Encode encodes the entity into an ISCN entity block.
+func (e *Entity) Encode() (iscn.IscnObject, error) {
+	data := map[string]interface{}{
+		"id": e.ID,
+	}
+	if len(e.Name) > 0 {
+		data["name"] = e.Name
+	}
+	if len(e.Description) > 0 {
+		data["description"] = e.Description
+	}
+
+	return iscn.Encode(iscn.CodecEntity, SchemaVersion, data)
+}
+
+// FromObject reads the entity from an ISCN entity block.
+func (e *Entity) FromObject(obj iscn.IscnObject) error {
+	return e.fromObject(obj).first()
+}
+
+// fromObject reads the entity, the properties which cannot be read are left
+// zero.
+func (e *Entity) fromObject(obj iscn.IscnObject) decodeErrors {
+	errs := decodeErrors{}
+	if err := checkCodec(obj, iscn.CodecEntity); err != nil {
+		return append(errs, err)
+	}
+
+	var err error
+	e.ID, err = GetString(obj, "id")
+	errs.add(err)
+	e.Name, err = getString(obj, "name")
+	errs.add(err)
+	e.Description, err = getString(obj, "description")
+	errs.add(err)
+
+	return errs
+}
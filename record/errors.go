@@ -0,0 +1,105 @@
+package record
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is the error of a missing property.
+	ErrNotFound = errors.New("not found")
+
+	// ErrTypeMismatch is the error of a property of an unexpected type.
+	ErrTypeMismatch = errors.New("type mismatch")
+
+	// ErrInvalidCodec is the error of a block of an unexpected codec.
+	ErrInvalidCodec = errors.New("invalid codec")
+)
+
+// PathError is the error of a property. It wraps one of ErrNotFound,
+// ErrTypeMismatch and ErrInvalidCodec, so it can be checked with errors.Is.
+type PathError struct {
+	// Path of the property relative to the block, e.g. "rights/1/period". It
+	// is empty for the block itself.
+	Path string
+
+	Err error
+
+	// Detail tells more about the error, it may be empty.
+	Detail string
+}
+
+func (e *PathError) Error() string {
+	msg := e.Err.Error()
+	if len(e.Path) > 0 {
+		msg = fmt.Sprintf("%q: %s", e.Path, msg)
+	}
+	if len(e.Detail) > 0 {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// nestedError prefixes the path of the error of a nested property.
+func nestedError(prefix string, err error) error {
+	var pathErr *PathError
+	if errors.As(err, &pathErr) {
+		nested := *pathErr
+		nested.Path = prefix
+		if len(pathErr.Path) > 0 {
+			nested.Path += "/" + pathErr.Path
+		}
+		return &nested
+	}
+	return fmt.Errorf("%q: %w", prefix, err)
+}
+
+// decodeErrors are the errors of the properties of a block. The decoding goes
+// on after an invalid property, so Validate reports them all.
+type decodeErrors []error
+
+func (errs *decodeErrors) add(err error) {
+	if err != nil {
+		*errs = append(*errs, err)
+	}
+}
+
+// nested adds the errors of a nested object with the path prefix.
+func (errs *decodeErrors) nested(prefix string, nested decodeErrors) {
+	for _, err := range nested {
+		*errs = append(*errs, nestedError(prefix, err))
+	}
+}
+
+// first returns the first error, which FromObject fails with.
+func (errs decodeErrors) first() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs[0]
+}
+
+// getterError converts the error of a getter of IscnObject. The getters only
+// tell a missing property by the text of the error, so it is only checked
+// here, and TestGetterNotFound fails if iscn-ipld changes the text.
+func getterError(err error, key string) error {
+	if err == nil {
+		return nil
+	}
+	if err.Error() == fmt.Sprintf("%q is not found", key) {
+		return &PathError{Path: key, Err: ErrNotFound}
+	}
+	return &PathError{Path: key, Err: ErrTypeMismatch, Detail: err.Error()}
+}
+
+// optional drops the error of a missing property.
+func optional(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package record
+
+import (
+	"errors"
+	"testing"
+)
+
+// The getters of IscnObject only tell a missing property by the text of the
+// error, so a change of the text in iscn-ipld breaks this test instead of the
+// records with missing optional properties.
+func TestGetterNotFound(t *testing.T) {
+	entity, err := (&Entity{ID: "lcc://id/demo"}).Encode()
+	if err != nil {
+		t.Fatalf("cannot encode entity: %s", err)
+	}
+
+	if _, err := GetString(entity, "description"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetString() of a missing property = %v, want ErrNotFound", err)
+	}
+	e := Entity{}
+	if err := e.FromObject(entity); err != nil {
+		t.Errorf("FromObject() of an entity without a name = %s", err)
+	}
+
+	content, err := (&Content{
+		Type:        "article",
+		Version:     1,
+		Fingerprint: "fp",
+		Title:       "ISCN Demo",
+	}).Encode()
+	if err != nil {
+		t.Fatalf("cannot encode content: %s", err)
+	}
+
+	if _, err := GetArray(content, "tags"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetArray() of a missing property = %v, want ErrNotFound", err)
+	}
+
+	c := Content{}
+	if err := c.FromObject(content); err != nil {
+		t.Errorf("FromObject() of a content without tags = %s", err)
+	}
+}
+
+func TestGetterTypeMismatch(t *testing.T) {
+	entity, err := (&Entity{ID: "lcc://id/demo"}).Encode()
+	if err != nil {
+		t.Fatalf("cannot encode entity: %s", err)
+	}
+
+	if _, err := GetUint64(entity, "id"); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("GetUint64() of a string property = %v, want ErrTypeMismatch", err)
+	}
+}
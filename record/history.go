@@ -0,0 +1,162 @@
+package record
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+
+	ipld "github.com/ipfs/go-ipld-format"
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// ContentVersion is a content version in a lineage.
+type ContentVersion struct {
+	Cid     cid.Cid
+	Content Content
+}
+
+// HistoryProblem is an inconsistency found in a lineage.
+type HistoryProblem struct {
+	Cid     cid.Cid
+	Message string
+}
+
+func (p HistoryProblem) Error() string {
+	return fmt.Sprintf("%s: %s", p.Cid, p.Message)
+}
+
+// History is the lineage of a content version.
+type History struct {
+	// Versions are the content version and its ancestors, the latest first.
+	Versions []ContentVersion
+
+	// Problems are the non-monotonic versions, the forks and the parents
+	// which cannot be fetched.
+	Problems []HistoryProblem
+}
+
+func (h *History) report(c cid.Cid, format string, args ...interface{}) {
+	h.Problems = append(h.Problems, HistoryProblem{
+		Cid:     c,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func fetchContent(
+	ctx context.Context,
+	getter ipld.NodeGetter,
+	c cid.Cid,
+) (*Content, error) {
+	nd, err := getter.Get(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch block: %s", err)
+	}
+	obj, err := iscn.Decode(nd.RawData(), c)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode block: %s", err)
+	}
+
+	content := &Content{}
+	if err := content.FromObject(obj); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// ContentHistory walks the parent chain of the content version. The version
+// of each content should be greater than the one of its parent. The known
+// content blocks, e.g. all the content blocks in the store, are checked for
+// the children claiming the same parent in the lineage.
+func ContentHistory(
+	ctx context.Context,
+	getter ipld.NodeGetter,
+	c cid.Cid,
+	known []cid.Cid,
+) (*History, error) {
+	if c.Type() != iscn.CodecContent {
+		return nil, &PathError{
+			Err:    ErrInvalidCodec,
+			Detail: fmt.Sprintf("block %s is not an ISCN content", c),
+		}
+	}
+
+	content, err := fetchContent(ctx, getter, c)
+	if err != nil {
+		return nil, fmt.Errorf("content %s: %w", c, err)
+	}
+
+	h := &History{}
+	visited := map[cid.Cid]bool{}
+	for {
+		h.Versions = append(h.Versions, ContentVersion{Cid: c, Content: *content})
+		visited[c] = true
+
+		parentCid := content.Parent
+		if !parentCid.Defined() {
+			break
+		}
+		if visited[parentCid] {
+			h.report(c, "parent %s is a descendant", parentCid)
+			break
+		}
+
+		parent, err := fetchContent(ctx, getter, parentCid)
+		if err != nil {
+			h.report(c, "parent %s: %s", parentCid, err)
+			break
+		}
+		if content.Version <= parent.Version {
+			h.report(
+				c,
+				"version %d is not greater than version %d of parent %s",
+				content.Version,
+				parent.Version,
+				parentCid,
+			)
+		}
+
+		c, content = parentCid, parent
+	}
+
+	if len(known) > 0 {
+		h.checkForks(ctx, getter, known)
+	}
+
+	return h, nil
+}
+
+// checkForks reports the versions in the lineage which are the parent of more
+// than one known content block.
+func (h *History) checkForks(
+	ctx context.Context,
+	getter ipld.NodeGetter,
+	known []cid.Cid,
+) {
+	inLineage := map[cid.Cid]bool{}
+	for _, v := range h.Versions {
+		inLineage[v.Cid] = true
+	}
+
+	children := map[cid.Cid][]cid.Cid{}
+	for _, c := range known {
+		if c.Type() != iscn.CodecContent {
+			continue
+		}
+
+		content, err := fetchContent(ctx, getter, c)
+		if err != nil {
+			h.report(c, "%s", err)
+			continue
+		}
+		if content.Parent.Defined() && inLineage[content.Parent] {
+			children[content.Parent] = append(children[content.Parent], c)
+		}
+	}
+
+	for _, v := range h.Versions {
+		if claims := children[v.Cid]; len(claims) > 1 {
+			h.report(v.Cid, "claimed as parent by %d contents %v", len(claims), claims)
+		}
+	}
+}
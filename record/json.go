@@ -0,0 +1,172 @@
+package record
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/ipfs/go-cid"
+
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// FromJSON decodes the JSON written by IscnObject.MarshalJSON into a block of
+// the codec and schema version. The links written as {"/": "<cid>"} become
+// CIDs and the bytes properties, e.g. the kernel ID, are decoded from base64,
+// so the block has the same CID as the one the JSON is written from.
+func FromJSON(
+	codec uint64,
+	schemaVersion uint64,
+	raw []byte,
+) (iscn.IscnObject, error) {
+	data, err := DecodeJSON(codec, schemaVersion, raw)
+	if err != nil {
+		return nil, err
+	}
+	return iscn.Encode(codec, schemaVersion, data)
+}
+
+// DecodeJSON decodes the JSON of a block of the codec and schema version into
+// the data map taken by iscn.Encode. The properties which are base64 strings
+// in the JSON Schema of the codec, i.e. the kernel ID and the custom
+// properties of TypeBytes in the registered namespaces, are decoded into
+// bytes. The bytes of an unregistered custom property stay strings, since the
+// JSON cannot tell them apart.
+func DecodeJSON(
+	codec uint64,
+	schemaVersion uint64,
+	raw []byte,
+) (map[string]interface{}, error) {
+	schema, err := JSONSchema(codec, schemaVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	data := map[string]interface{}{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("cannot parse JSON: %s", err)
+	}
+
+	for key, value := range data {
+		v, err := fromJSONValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %s", key, err)
+		}
+		data[key] = v
+	}
+
+	if _, err := decodeBytes("", schema, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// decodeBytes decodes the base64 strings of the value where the schema has a
+// base64 content encoding. The objects and the arrays are decoded in place.
+func decodeBytes(path string, schema jsonSchema, value interface{}) (interface{}, error) {
+	if schema["contentEncoding"] == "base64" {
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%q: bytes should be a base64 string", path)
+		}
+		b, err := base64.StdEncoding.DecodeString(str)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %s", path, err)
+		}
+		return b, nil
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(jsonSchema)
+		for key, val := range v {
+			property, ok := properties[key].(jsonSchema)
+			if !ok {
+				continue
+			}
+			decoded, err := decodeBytes(joinPath(path, key), property, val)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = decoded
+		}
+	case []map[string]interface{}:
+		items, _ := schema["items"].(jsonSchema)
+		for i, val := range v {
+			if _, err := decodeBytes(joinPath(path, strconv.Itoa(i)), items, val); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		items, _ := schema["items"].(jsonSchema)
+		for i, val := range v {
+			decoded, err := decodeBytes(joinPath(path, strconv.Itoa(i)), items, val)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = decoded
+		}
+	}
+	return value, nil
+}
+
+func fromJSONValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return int(i), nil
+		}
+		return v.Float64()
+	case map[string]interface{}:
+		if link, ok := v["/"]; ok && len(v) == 1 {
+			str, ok := link.(string)
+			if !ok {
+				return nil, errors.New("link should be a string")
+			}
+			return cid.Decode(str)
+		}
+
+		for key, value := range v {
+			val, err := fromJSONValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("%q: %s", key, err)
+			}
+			v[key] = val
+		}
+		return v, nil
+	case []interface{}:
+		objects := []map[string]interface{}{}
+		strs := []string{}
+		for i, value := range v {
+			val, err := fromJSONValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("(Index %d) %s", i, err)
+			}
+			v[i] = val
+
+			switch e := val.(type) {
+			case map[string]interface{}:
+				objects = append(objects, e)
+			case string:
+				strs = append(strs, e)
+			}
+		}
+
+		// The encoder expects homogeneous arrays to be typed
+		if len(v) > 0 && len(objects) == len(v) {
+			return objects, nil
+		}
+		if len(v) > 0 && len(strs) == len(v) {
+			return strs, nil
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
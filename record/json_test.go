@@ -0,0 +1,121 @@
+package record
+
+import (
+	"testing"
+	"time"
+
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// demoBlocks encodes the blocks of a registration like the demo one.
+func demoBlocks(t *testing.T) []iscn.IscnObject {
+	t.Helper()
+
+	entity, err := (&Entity{ID: "lcc://id/demo", Name: "Demo"}).Encode()
+	if err != nil {
+		t.Fatalf("cannot encode entity: %s", err)
+	}
+
+	rights, err := (&Rights{
+		Rights: []Right{
+			{
+				Holder: entity.Cid(),
+				Type:   "license",
+				Terms:  entity.Cid(),
+				Period: &Period{
+					From: time.Date(2020, 1, 1, 12, 34, 56, 0, time.UTC),
+					To:   time.Date(2046, 1, 1, 12, 34, 56, 0, time.FixedZone("", 8*60*60)),
+				},
+				Territory: "Mars",
+			},
+		},
+	}).Encode()
+	if err != nil {
+		t.Fatalf("cannot encode rights: %s", err)
+	}
+
+	stakeholders, err := (&Stakeholders{
+		Stakeholders: []Stakeholder{
+			{
+				Type:        "Creator",
+				Stakeholder: entity.Cid(),
+				Sharing:     1,
+				Footprint:   Link{URL: "https://example.com/footprint.html"},
+			},
+		},
+	}).Encode()
+	if err != nil {
+		t.Fatalf("cannot encode stakeholders: %s", err)
+	}
+
+	content, err := (&Content{
+		Type:        "article",
+		Version:     1,
+		Fingerprint: "hash://sha256/9564b85669d5e96ac969dd0161b8475bbced9e5999c6ec598da718a3045d6f2e",
+		Title:       "ISCN Demo",
+		Tags:        []string{"demo", "iscn"},
+	}).Encode()
+	if err != nil {
+		t.Fatalf("cannot encode content: %s", err)
+	}
+
+	id := make([]byte, KernelIDLength)
+	for i := range id {
+		id[i] = byte(i)
+	}
+	kernel, err := (&Kernel{
+		ID:           id,
+		Timestamp:    time.Date(2020, 1, 1, 12, 34, 56, 0, time.UTC),
+		Version:      1,
+		Rights:       rights.Cid(),
+		Stakeholders: stakeholders.Cid(),
+		Content:      content.Cid(),
+		Custom:       demoProperties(),
+	}).Encode()
+	if err != nil {
+		t.Fatalf("cannot encode kernel: %s", err)
+	}
+
+	return []iscn.IscnObject{kernel, rights, stakeholders, content, entity}
+}
+
+func TestFromJSONKeepsCid(t *testing.T) {
+	for _, obj := range demoBlocks(t) {
+		raw, err := obj.MarshalJSON()
+		if err != nil {
+			t.Fatalf("%s: cannot marshal JSON: %s", obj.GetName(), err)
+		}
+
+		decoded, err := FromJSON(obj.Cid().Type(), obj.GetVersion(), raw)
+		if err != nil {
+			t.Fatalf("%s: FromJSON() = %s", obj.GetName(), err)
+		}
+		if !decoded.Cid().Equals(obj.Cid()) {
+			t.Errorf(
+				"%s: FromJSON() gives CID %s, want %s\n%s",
+				obj.GetName(),
+				decoded.Cid(),
+				obj.Cid(),
+				raw,
+			)
+		}
+	}
+}
+
+func TestDecodeJSONBytes(t *testing.T) {
+	kernel := demoBlocks(t)[0]
+	raw, err := kernel.MarshalJSON()
+	if err != nil {
+		t.Fatalf("cannot marshal JSON: %s", err)
+	}
+
+	data, err := DecodeJSON(iscn.CodecISCN, SchemaVersion, raw)
+	if err != nil {
+		t.Fatalf("DecodeJSON() = %s", err)
+	}
+	for _, key := range []string{"id", "demo:xxx"} {
+		if _, ok := data[key].([]byte); !ok {
+			t.Errorf("%q is %T, want []byte", key, data[key])
+		}
+	}
+}
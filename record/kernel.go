@@ -0,0 +1,84 @@
+package record
+
+import (
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// Kernel is the ISCN kernel which links the rights, the stakeholders and the
+// content of a registration.
+type Kernel struct {
+	ID           []byte
+	Timestamp    time.Time
+	Version      uint64
+	Rights       cid.Cid
+	Stakeholders cid.Cid
+	Content      cid.Cid
+
+	// Custom holds the custom properties of the kernel, the properties of
+	// the registered namespaces are validated against their schemas.
+	Custom Properties
+}
+
+// Encode encodes the kernel into an ISCN kernel block.
+func (k *Kernel) Encode() (iscn.IscnObject, error) {
+	if err := k.Custom.Validate(); err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{}
+	for key, value := range k.Custom {
+		data[key] = value
+	}
+	delete(data, "timestamp"+OffsetSuffix)
+
+	data["id"] = k.ID
+	putTime(data, "timestamp", k.Timestamp)
+	data["version"] = k.Version
+	data["rights"] = k.Rights
+	data["stakeholders"] = k.Stakeholders
+	data["content"] = k.Content
+
+	return iscn.Encode(iscn.CodecISCN, SchemaVersion, data)
+}
+
+// FromObject reads the kernel from an ISCN kernel block.
+func (k *Kernel) FromObject(obj iscn.IscnObject) error {
+	return k.fromObject(obj).first()
+}
+
+// fromObject reads the kernel, the properties which cannot be read are left
+// zero.
+func (k *Kernel) fromObject(obj iscn.IscnObject) decodeErrors {
+	errs := decodeErrors{}
+	if err := checkCodec(obj, iscn.CodecISCN); err != nil {
+		return append(errs, err)
+	}
+
+	var err error
+	k.ID, err = GetBytes(obj, "id")
+	errs.add(err)
+	k.Timestamp, err = GetTime(obj, "timestamp")
+	errs.add(err)
+	k.Version, err = GetUint64(obj, "version")
+	errs.add(err)
+	k.Rights, err = GetCid(obj, "rights")
+	errs.add(err)
+	k.Stakeholders, err = GetCid(obj, "stakeholders")
+	errs.add(err)
+	k.Content, err = GetCid(obj, "content")
+	errs.add(err)
+
+	// The original offset of the timestamp is not a custom property
+	k.Custom = Properties{}
+	for key, value := range obj.GetCustom() {
+		if key != "timestamp"+OffsetSuffix {
+			k.Custom[key] = value
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,266 @@
+package record
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+
+	ipld "github.com/ipfs/go-ipld-format"
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// Upgrader upgrades the data of a block by one schema version. The data is
+// decoded as by DecodeJSON, with the links already pointing to the upgraded
+// blocks.
+type Upgrader func(data map[string]interface{}) (map[string]interface{}, error)
+
+var (
+	upgradersLock sync.RWMutex
+
+	// upgraders are keyed by the codec and the version upgraded from
+	upgraders = map[uint64]map[uint64]Upgrader{}
+)
+
+// encode encodes the upgraded blocks. It is replaced in the tests, as the
+// codec only encodes the schema versions it knows.
+var encode = iscn.Encode
+
+// RegisterUpgrader registers the upgrader of the blocks of the codec from the
+// schema version from to the version from+1.
+func RegisterUpgrader(codec uint64, from uint64, upgrade Upgrader) {
+	upgradersLock.Lock()
+	defer upgradersLock.Unlock()
+
+	if upgraders[codec] == nil {
+		upgraders[codec] = map[uint64]Upgrader{}
+	}
+	upgraders[codec][from] = upgrade
+}
+
+func upgrader(codec uint64, from uint64) (Upgrader, bool) {
+	upgradersLock.RLock()
+	defer upgradersLock.RUnlock()
+
+	upgrade, ok := upgraders[codec][from]
+	return upgrade, ok
+}
+
+// upgradeData runs the upgraders of the codec from the schema version from
+// to the version to.
+func upgradeData(
+	codec uint64,
+	from uint64,
+	to uint64,
+	data map[string]interface{},
+) (map[string]interface{}, error) {
+	for v := from; v < to; v++ {
+		upgrade, ok := upgrader(codec, v)
+		if !ok {
+			return nil, fmt.Errorf(
+				"no upgrader of %s blocks from schema version %d",
+				codecNames[codec],
+				v,
+			)
+		}
+
+		var err error
+		if data, err = upgrade(data); err != nil {
+			return nil, fmt.Errorf(
+				"cannot upgrade %s block from schema version %d: %s",
+				codecNames[codec],
+				v,
+				err,
+			)
+		}
+	}
+	return data, nil
+}
+
+// Upgrade upgrades a block to the schema version to, the links of the block
+// are kept as they are. The block is returned as is if it is already of the
+// version.
+func Upgrade(obj iscn.IscnObject, to uint64) (iscn.IscnObject, error) {
+	from := obj.GetVersion()
+	if from == to {
+		return obj, nil
+	}
+	if from > to {
+		return nil, fmt.Errorf(
+			"cannot downgrade block %s from schema version %d to %d",
+			obj.Cid(),
+			from,
+			to,
+		)
+	}
+
+	codec := obj.Cid().Type()
+	data, err := blockData(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err = upgradeData(codec, from, to, data); err != nil {
+		return nil, err
+	}
+	return encode(codec, to, data)
+}
+
+// relink replaces the links in the data of a block.
+func relink(value interface{}, mapping func(cid.Cid) cid.Cid) interface{} {
+	switch v := value.(type) {
+	case cid.Cid:
+		return mapping(v)
+	case map[string]interface{}:
+		for key, val := range v {
+			v[key] = relink(val, mapping)
+		}
+		return v
+	case []map[string]interface{}:
+		for _, val := range v {
+			relink(val, mapping)
+		}
+		return v
+	case []interface{}:
+		for i, val := range v {
+			v[i] = relink(val, mapping)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// links lists the links to the ISCN blocks in the data of a block. The
+// footprints link to other registrations, so they are not followed.
+func links(value interface{}, found []cid.Cid) []cid.Cid {
+	switch v := value.(type) {
+	case cid.Cid:
+		if IsISCN(v) {
+			found = append(found, v)
+		}
+	case map[string]interface{}:
+		for key, val := range v {
+			if key != "footprint" {
+				found = links(val, found)
+			}
+		}
+	case []map[string]interface{}:
+		for _, val := range v {
+			found = links(val, found)
+		}
+	case []interface{}:
+		for _, val := range v {
+			found = links(val, found)
+		}
+	}
+	return found
+}
+
+// Migrator migrates the ISCN blocks to a schema version. Since a link is the
+// hash of the block, a block linking to an upgraded block is rewritten too.
+// A block already of the version is kept as it is and its links are not
+// followed, the blocks it links to are migrated when they are migrated
+// themselves.
+type Migrator struct {
+	getter ipld.NodeGetter
+	to     uint64
+
+	// migrated maps every visited CID to its new CID
+	migrated map[cid.Cid]cid.Cid
+	objects  []iscn.IscnObject
+}
+
+// NewMigrator creates a migrator to the schema version to which fetches the
+// blocks with the getter.
+func NewMigrator(getter ipld.NodeGetter, to uint64) *Migrator {
+	return &Migrator{
+		getter:   getter,
+		to:       to,
+		migrated: map[cid.Cid]cid.Cid{},
+	}
+}
+
+// Migrate migrates the block of the CID and the ISCN blocks it links to, and
+// returns the new CID. The CID is returned as is if nothing is changed.
+func (m *Migrator) Migrate(ctx context.Context, c cid.Cid) (cid.Cid, error) {
+	if migrated, ok := m.migrated[c]; ok {
+		return migrated, nil
+	}
+
+	nd, err := m.getter.Get(ctx, c)
+	if err != nil {
+		return cid.Undef, fmt.Errorf("cannot fetch block %s: %s", c, err)
+	}
+	obj, err := iscn.Decode(nd.RawData(), c)
+	if err != nil {
+		return cid.Undef, fmt.Errorf("cannot decode block %s: %s", c, err)
+	}
+
+	if obj.GetVersion() > m.to {
+		return cid.Undef, fmt.Errorf(
+			"cannot downgrade block %s from schema version %d to %d",
+			c,
+			obj.GetVersion(),
+			m.to,
+		)
+	}
+	if obj.GetVersion() == m.to {
+		m.migrated[c] = c
+		return c, nil
+	}
+
+	data, err := blockData(obj)
+	if err != nil {
+		return cid.Undef, fmt.Errorf("cannot decode block %s: %s", c, err)
+	}
+
+	// The linked blocks are migrated first
+	relinked := map[cid.Cid]cid.Cid{}
+	for _, link := range links(data, nil) {
+		newLink, err := m.Migrate(ctx, link)
+		if err != nil {
+			return cid.Undef, err
+		}
+		if !newLink.Equals(link) {
+			relinked[link] = newLink
+		}
+	}
+
+	relink(data, func(link cid.Cid) cid.Cid {
+		if newLink, ok := relinked[link]; ok {
+			return newLink
+		}
+		return link
+	})
+
+	if data, err = upgradeData(c.Type(), obj.GetVersion(), m.to, data); err != nil {
+		return cid.Undef, fmt.Errorf("block %s: %s", c, err)
+	}
+
+	newObj, err := encode(c.Type(), m.to, data)
+	if err != nil {
+		return cid.Undef, fmt.Errorf("cannot encode block %s: %s", c, err)
+	}
+
+	m.migrated[c] = newObj.Cid()
+	m.objects = append(m.objects, newObj)
+	return newObj.Cid(), nil
+}
+
+// Mapping returns the old and the new CIDs of the rewritten blocks.
+func (m *Migrator) Mapping() map[cid.Cid]cid.Cid {
+	mapping := map[cid.Cid]cid.Cid{}
+	for old, migrated := range m.migrated {
+		if !old.Equals(migrated) {
+			mapping[old] = migrated
+		}
+	}
+	return mapping
+}
+
+// Objects returns the rewritten blocks in dependency order.
+func (m *Migrator) Objects() []iscn.IscnObject {
+	return m.objects
+}
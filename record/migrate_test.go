@@ -0,0 +1,209 @@
+package record
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+
+	ipld "github.com/ipfs/go-ipld-format"
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// memGetter is a NodeGetter of the blocks in memory.
+type memGetter map[cid.Cid]ipld.Node
+
+func newMemGetter(objs []iscn.IscnObject) memGetter {
+	g := memGetter{}
+	for _, obj := range objs {
+		g[obj.Cid()] = obj
+	}
+	return g
+}
+
+func (g memGetter) Get(ctx context.Context, c cid.Cid) (ipld.Node, error) {
+	nd, ok := g[c]
+	if !ok {
+		return nil, ipld.ErrNotFound
+	}
+	return nd, nil
+}
+
+func (g memGetter) GetMany(ctx context.Context, cids []cid.Cid) <-chan *ipld.NodeOption {
+	out := make(chan *ipld.NodeOption, len(cids))
+	for _, c := range cids {
+		nd, err := g.Get(ctx, c)
+		out <- &ipld.NodeOption{Node: nd, Err: err}
+	}
+	close(out)
+	return out
+}
+
+// useTestVersion registers the upgraders to the test version, which is
+// encoded as the current one. The entities are upgraded if upgradeEntities
+// is set, so their CIDs change. The returned function restores the upgraders.
+func useTestVersion(upgradeEntities bool) (uint64, func()) {
+	encode = func(
+		codec uint64,
+		version uint64,
+		data map[string]interface{},
+	) (iscn.IscnObject, error) {
+		return iscn.Encode(codec, SchemaVersion, data)
+	}
+
+	keep := func(data map[string]interface{}) (map[string]interface{}, error) {
+		return data, nil
+	}
+	for codec := range codecNames {
+		RegisterUpgrader(codec, SchemaVersion, keep)
+	}
+	if upgradeEntities {
+		RegisterUpgrader(
+			iscn.CodecEntity,
+			SchemaVersion,
+			func(data map[string]interface{}) (map[string]interface{}, error) {
+				data["description"] = "upgraded"
+				return data, nil
+			},
+		)
+	}
+
+	return SchemaVersion + 1, func() {
+		encode = iscn.Encode
+		for codec := range codecNames {
+			upgradersLock.Lock()
+			delete(upgraders[codec], SchemaVersion)
+			upgradersLock.Unlock()
+		}
+	}
+}
+
+func TestMigratorRelinks(t *testing.T) {
+	testVersion, restore := useTestVersion(true)
+	defer restore()
+
+	objs := demoBlocks(t)
+	kernel, rights, stakeholders, content, entity := objs[0], objs[1], objs[2], objs[3], objs[4]
+
+	m := NewMigrator(newMemGetter(objs), testVersion)
+	migrated, err := m.Migrate(context.Background(), kernel.Cid())
+	if err != nil {
+		t.Fatalf("Migrate() = %s", err)
+	}
+
+	mapping := m.Mapping()
+	for _, obj := range []iscn.IscnObject{kernel, rights, stakeholders, entity} {
+		if _, ok := mapping[obj.Cid()]; !ok {
+			t.Errorf("%s %s is not rewritten", obj.GetName(), obj.Cid())
+		}
+	}
+	if _, ok := mapping[content.Cid()]; ok {
+		t.Errorf("content %s is rewritten, it links to no upgraded block", content.Cid())
+	}
+	if !migrated.Equals(mapping[kernel.Cid()]) {
+		t.Errorf("Migrate() = %s, want %s", migrated, mapping[kernel.Cid()])
+	}
+
+	rewritten := map[cid.Cid]iscn.IscnObject{}
+	for _, obj := range m.Objects() {
+		rewritten[obj.Cid()] = obj
+	}
+
+	newKernel := Kernel{}
+	if err := newKernel.FromObject(rewritten[migrated]); err != nil {
+		t.Fatalf("cannot read migrated kernel: %s", err)
+	}
+	if !newKernel.Rights.Equals(mapping[rights.Cid()]) {
+		t.Errorf("kernel links to rights %s, want %s", newKernel.Rights, mapping[rights.Cid()])
+	}
+	if !newKernel.Stakeholders.Equals(mapping[stakeholders.Cid()]) {
+		t.Errorf(
+			"kernel links to stakeholders %s, want %s",
+			newKernel.Stakeholders,
+			mapping[stakeholders.Cid()],
+		)
+	}
+	if !newKernel.Content.Equals(content.Cid()) {
+		t.Errorf("kernel links to content %s, want %s", newKernel.Content, content.Cid())
+	}
+
+	newRights := Rights{}
+	if err := newRights.FromObject(rewritten[mapping[rights.Cid()]]); err != nil {
+		t.Fatalf("cannot read migrated rights: %s", err)
+	}
+	if holder := newRights.Rights[0].Holder; !holder.Equals(mapping[entity.Cid()]) {
+		t.Errorf("right holder is %s, want %s", holder, mapping[entity.Cid()])
+	}
+
+	newEntity := Entity{}
+	if err := newEntity.FromObject(rewritten[mapping[entity.Cid()]]); err != nil {
+		t.Fatalf("cannot read migrated entity: %s", err)
+	}
+	if newEntity.Description != "upgraded" {
+		t.Errorf("entity description is %q, want \"upgraded\"", newEntity.Description)
+	}
+}
+
+func TestMigratorKeepsCurrentVersion(t *testing.T) {
+	kernel := demoBlocks(t)[0]
+
+	// The linked blocks cannot be fetched, they are not followed
+	m := NewMigrator(newMemGetter([]iscn.IscnObject{kernel}), SchemaVersion)
+	migrated, err := m.Migrate(context.Background(), kernel.Cid())
+	if err != nil {
+		t.Fatalf("Migrate() = %s", err)
+	}
+	if !migrated.Equals(kernel.Cid()) {
+		t.Errorf("Migrate() = %s, want %s", migrated, kernel.Cid())
+	}
+	if len(m.Objects()) != 0 {
+		t.Errorf("%d blocks are rewritten, want none", len(m.Objects()))
+	}
+}
+
+func TestMigratorSkipsFootprints(t *testing.T) {
+	testVersion, restore := useTestVersion(false)
+	defer restore()
+
+	objs := demoBlocks(t)
+	entity := objs[4]
+
+	// The footprint links to a kernel of another registration, which is not
+	// in the getter
+	stakeholders, err := (&Stakeholders{
+		Stakeholders: []Stakeholder{
+			{
+				Type:        "Creator",
+				Stakeholder: entity.Cid(),
+				Sharing:     1,
+				Footprint:   Link{Cid: objs[0].Cid()},
+			},
+		},
+	}).Encode()
+	if err != nil {
+		t.Fatalf("cannot encode stakeholders: %s", err)
+	}
+
+	getter := newMemGetter([]iscn.IscnObject{stakeholders, entity})
+	m := NewMigrator(getter, testVersion)
+	migrated, err := m.Migrate(context.Background(), stakeholders.Cid())
+	if err != nil {
+		t.Fatalf("Migrate() = %s", err)
+	}
+
+	newStakeholders := Stakeholders{}
+	for _, obj := range m.Objects() {
+		if obj.Cid().Equals(migrated) {
+			if err := newStakeholders.FromObject(obj); err != nil {
+				t.Fatalf("cannot read migrated stakeholders: %s", err)
+			}
+		}
+	}
+	if len(newStakeholders.Stakeholders) != 1 {
+		t.Fatalf("migrated stakeholders %s is not rewritten", migrated)
+	}
+	footprint := newStakeholders.Stakeholders[0].Footprint
+	if !footprint.Cid.Equals(objs[0].Cid()) {
+		t.Errorf("footprint is %s, want %s", footprint.Cid, objs[0].Cid())
+	}
+}
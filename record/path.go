@@ -0,0 +1,262 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+
+	blocks "github.com/ipfs/go-block-format"
+	ipld "github.com/ipfs/go-ipld-format"
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// blockData decodes the data of a block as DecodeJSON does.
+func blockData(obj iscn.IscnObject) (map[string]interface{}, error) {
+	raw, err := obj.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return DecodeJSON(obj.Cid().Type(), obj.GetVersion(), raw)
+}
+
+// child returns the property of an object or the element of an array.
+func child(value interface{}, name string) (interface{}, bool) {
+	index := func(length int) (int, bool) {
+		i, err := strconv.Atoi(name)
+		return i, err == nil && i >= 0 && i < length
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		val, ok := v[name]
+		return val, ok
+	case []map[string]interface{}:
+		if i, ok := index(len(v)); ok {
+			return v[i], true
+		}
+	case []interface{}:
+		if i, ok := index(len(v)); ok {
+			return v[i], true
+		}
+	case []string:
+		if i, ok := index(len(v)); ok {
+			return v[i], true
+		}
+	}
+	return nil, false
+}
+
+// arrayOf returns the array of a block of a single array, e.g. the rights
+// block, if the name is not a property of the block, so a path can index the
+// array directly, e.g. "1/period" for "rights/1/period". Otherwise the data
+// of the block is returned.
+func arrayOf(data map[string]interface{}, name string) interface{} {
+	if _, ok := data[name]; ok || len(data) != 1 {
+		return data
+	}
+	for _, value := range data {
+		if _, ok := elements(value); ok {
+			return value
+		}
+	}
+	return data
+}
+
+// Resolve resolves the path inside the block as ipld.Node does. When a link
+// is met before the end of the path, the link is returned with the rest of
+// the path to resolve in the linked block. A link at the end of the path is
+// returned as an *ipld.Link. The array of the rights and the stakeholders
+// blocks can be indexed directly, e.g. "/<kernel cid>/rights/1/period" is the
+// same as "/<kernel cid>/rights/rights/1/period".
+func Resolve(obj iscn.IscnObject, path []string) (interface{}, []string, error) {
+	data, err := blockData(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var value interface{} = data
+	for i, name := range path {
+		if c, ok := value.(cid.Cid); ok {
+			return &ipld.Link{Cid: c}, path[i:], nil
+		}
+		if i == 0 {
+			value = arrayOf(data, name)
+		}
+
+		val, ok := child(value, name)
+		if !ok {
+			return nil, nil, &PathError{
+				Path: strings.Join(path[:i+1], "/"),
+				Err:  ErrNotFound,
+			}
+		}
+		value = val
+	}
+
+	if c, ok := value.(cid.Cid); ok {
+		return &ipld.Link{Cid: c}, nil, nil
+	}
+	return value, nil, nil
+}
+
+// tree lists the paths under the value down to depth levels.
+func tree(prefix string, value interface{}, depth int, paths []string) []string {
+	if depth == 0 {
+		return paths
+	}
+
+	names := []string{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	case []map[string]interface{}:
+		for i := range v {
+			names = append(names, strconv.Itoa(i))
+		}
+	case []interface{}:
+		for i := range v {
+			names = append(names, strconv.Itoa(i))
+		}
+	case []string:
+		for i := range v {
+			names = append(names, strconv.Itoa(i))
+		}
+	}
+
+	for _, name := range names {
+		path := joinPath(prefix, name)
+		paths = append(paths, path)
+
+		val, _ := child(value, name)
+		paths = tree(path, val, depth-1, paths)
+	}
+	return paths
+}
+
+// Tree lists the paths inside the block under the path down to depth levels
+// as ipld.Node does, a negative depth has no limit. The links are not
+// followed.
+func Tree(obj iscn.IscnObject, path string, depth int) []string {
+	data, err := blockData(obj)
+	if err != nil {
+		return nil
+	}
+
+	var value interface{} = data
+	prefix := strings.Trim(path, "/")
+	if len(prefix) > 0 {
+		for _, name := range strings.Split(prefix, "/") {
+			if value, _ = child(value, name); value == nil {
+				return nil
+			}
+		}
+	}
+
+	return tree(prefix, value, depth, nil)
+}
+
+// ParsePath splits a path such as "/<kernel cid>/rights/1/period/from" into
+// the CID of the first block and the path inside it.
+func ParsePath(path string) (cid.Cid, []string, error) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	c, err := cid.Decode(segments[0])
+	if err != nil {
+		return cid.Undef, nil, fmt.Errorf("cannot parse CID %q: %s", segments[0], err)
+	}
+	return c, segments[1:], nil
+}
+
+// Get queries the value of the path such as "/<kernel cid>/rights/1/period/from".
+// It goes through the links to the next blocks as needed, the blocks which
+// are not ISCN blocks are resolved by their own codecs. A link at the end of
+// the path is returned as a CID.
+func Get(ctx context.Context, getter ipld.NodeGetter, path string) (interface{}, error) {
+	c, rest, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		nd, err := getter.Get(ctx, c)
+		if err != nil {
+			return nil, fmt.Errorf("cannot fetch block %s: %s", c, err)
+		}
+
+		var value interface{}
+		if IsISCN(c) {
+			var obj iscn.IscnObject
+			obj, err = iscn.Decode(nd.RawData(), c)
+			if err != nil {
+				return nil, fmt.Errorf("cannot decode block %s: %s", c, err)
+			}
+			value, rest, err = Resolve(obj, rest)
+		} else {
+			value, rest, err = nd.Resolve(rest)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("block %s: %w", c, err)
+		}
+
+		link, ok := value.(*ipld.Link)
+		if !ok {
+			return value, nil
+		}
+		if len(rest) == 0 {
+			return link.Cid, nil
+		}
+		c = link.Cid
+	}
+}
+
+// pathNode is a decoded ISCN block whose paths are resolved by Resolve and
+// Tree.
+type pathNode struct {
+	iscn.IscnObject
+}
+
+func (nd pathNode) Resolve(path []string) (interface{}, []string, error) {
+	return Resolve(nd.IscnObject, path)
+}
+
+func (nd pathNode) Tree(path string, depth int) []string {
+	return Tree(nd.IscnObject, path, depth)
+}
+
+func (nd pathNode) ResolveLink(path []string) (*ipld.Link, []string, error) {
+	value, rest, err := nd.Resolve(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	link, ok := value.(*ipld.Link)
+	if !ok {
+		return nil, nil, errors.New("not a link")
+	}
+	return link, rest, nil
+}
+
+func decodePathNode(b blocks.Block) (ipld.Node, error) {
+	obj, err := iscn.Decode(b.RawData(), b.Cid())
+	if err != nil {
+		return nil, err
+	}
+	return pathNode{obj}, nil
+}
+
+// RegisterDecoders registers the decoders of the ISCN codecs, whose blocks
+// resolve their paths by Resolve and Tree, e.g. for "ipfs dag get <path>". It
+// replaces the decoders of the iscn-ipld plugin, so it is called after the
+// plugins are injected.
+func RegisterDecoders(decoder ipld.BlockDecoder) {
+	for codec := range codecNames {
+		decoder.Register(codec, decodePathNode)
+	}
+}
@@ -0,0 +1,57 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	objs := demoBlocks(t)
+	kernel := objs[0]
+	getter := newMemGetter(objs)
+	ctx := context.Background()
+
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"/" + kernel.Cid().String() + "/rights/rights/0/territory", "Mars"},
+		{"/" + kernel.Cid().String() + "/rights/0/period/from", "2020-01-01T12:34:56Z"},
+		{"/" + kernel.Cid().String() + "/stakeholders/stakeholders/0/stakeholder/name", "Demo"},
+		{"/" + kernel.Cid().String() + "/stakeholders/0/stakeholder/name", "Demo"},
+		{"/" + kernel.Cid().String() + "/content/title", "ISCN Demo"},
+	}
+	for _, test := range tests {
+		value, err := Get(ctx, getter, test.path)
+		if err != nil {
+			t.Errorf("Get(%q) = %s", test.path, err)
+			continue
+		}
+		if value != test.want {
+			t.Errorf("Get(%q) = %v, want %v", test.path, value, test.want)
+		}
+	}
+
+	link, err := Get(ctx, getter, "/"+kernel.Cid().String()+"/rights")
+	if err != nil || link != objs[1].Cid() {
+		t.Errorf("Get(\"/<kernel>/rights\") = %v, %v, want %s", link, err, objs[1].Cid())
+	}
+}
+
+func TestGetMissingPath(t *testing.T) {
+	objs := demoBlocks(t)
+	kernel := objs[0]
+	getter := newMemGetter(objs)
+
+	for _, path := range []string{
+		"/" + kernel.Cid().String() + "/missing",
+		"/" + kernel.Cid().String() + "/rights/5/type",
+		"/" + kernel.Cid().String() + "/rights/rights/0/missing",
+	} {
+		value, err := Get(context.Background(), getter, path)
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) = %v, %v, want ErrNotFound", path, value, err)
+		}
+	}
+}
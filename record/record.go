@@ -0,0 +1,180 @@
+// Package record provides the typed ISCN records on top of the ISCN IPLD
+// blocks.
+package record
+
+import (
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// SchemaVersion is the schema version of the blocks encoded by this package.
+// Version 2 stores the timestamps in UTC with their original offsets, the
+// blocks of version 1 are upgraded by the registered upgraders.
+const SchemaVersion = 2
+
+// checkCodec checks that the block is of the expected codec.
+func checkCodec(obj iscn.IscnObject, codec uint64) error {
+	if t := obj.Cid().Type(); t != codec {
+		return &PathError{
+			Err:    ErrInvalidCodec,
+			Detail: fmt.Sprintf("block %s is of codec 0x%x, not 0x%x", obj.Cid(), t, codec),
+		}
+	}
+	return nil
+}
+
+// GetString reads a string property. The error is a *PathError.
+func GetString(obj iscn.IscnObject, key string) (string, error) {
+	val, err := obj.GetString(key)
+	return val, getterError(err, key)
+}
+
+// LookupString reads an optional string property, ok is false if the
+// property is missing or is not a string.
+func LookupString(obj iscn.IscnObject, key string) (val string, ok bool) {
+	val, err := GetString(obj, key)
+	return val, err == nil
+}
+
+// GetUint64 reads an unsigned integer property. The error is a *PathError.
+func GetUint64(obj iscn.IscnObject, key string) (uint64, error) {
+	val, err := obj.GetUint64(key)
+	return val, getterError(err, key)
+}
+
+// LookupUint64 reads an optional unsigned integer property.
+func LookupUint64(obj iscn.IscnObject, key string) (val uint64, ok bool) {
+	val, err := GetUint64(obj, key)
+	return val, err == nil
+}
+
+// GetUint32 reads a 32-bit unsigned integer property. The error is a
+// *PathError.
+func GetUint32(obj iscn.IscnObject, key string) (uint32, error) {
+	val, err := obj.GetUint32(key)
+	return val, getterError(err, key)
+}
+
+// LookupUint32 reads an optional 32-bit unsigned integer property.
+func LookupUint32(obj iscn.IscnObject, key string) (val uint32, ok bool) {
+	val, err := GetUint32(obj, key)
+	return val, err == nil
+}
+
+// GetBytes reads a bytes property. The error is a *PathError.
+func GetBytes(obj iscn.IscnObject, key string) ([]byte, error) {
+	val, err := obj.GetBytes(key)
+	return val, getterError(err, key)
+}
+
+// LookupBytes reads an optional bytes property.
+func LookupBytes(obj iscn.IscnObject, key string) (val []byte, ok bool) {
+	val, err := GetBytes(obj, key)
+	return val, err == nil
+}
+
+// GetCid reads a CID property. The error is a *PathError.
+func GetCid(obj iscn.IscnObject, key string) (cid.Cid, error) {
+	val, err := obj.GetCid(key)
+	return val, getterError(err, key)
+}
+
+// LookupCid reads an optional CID property.
+func LookupCid(obj iscn.IscnObject, key string) (val cid.Cid, ok bool) {
+	val, err := GetCid(obj, key)
+	return val, err == nil
+}
+
+// GetArray reads an array property. The error is a *PathError.
+func GetArray(obj iscn.IscnObject, key string) ([]interface{}, error) {
+	val, err := obj.GetArray(key)
+	return val, getterError(err, key)
+}
+
+// LookupArray reads an optional array property.
+func LookupArray(obj iscn.IscnObject, key string) (val []interface{}, ok bool) {
+	val, err := GetArray(obj, key)
+	return val, err == nil
+}
+
+// GetObject reads an object property. The error is a *PathError.
+func GetObject(obj iscn.IscnObject, key string) (iscn.IscnObject, error) {
+	val, err := obj.GetObject(key)
+	if err != nil {
+		return nil, getterError(err, key)
+	}
+
+	o, ok := val.(iscn.IscnObject)
+	if !ok {
+		return nil, &PathError{
+			Path:   key,
+			Err:    ErrTypeMismatch,
+			Detail: fmt.Sprintf("%T is not an object", val),
+		}
+	}
+	return o, nil
+}
+
+// LookupObject reads an optional object property.
+func LookupObject(obj iscn.IscnObject, key string) (val iscn.IscnObject, ok bool) {
+	val, err := GetObject(obj, key)
+	return val, err == nil
+}
+
+// getString reads an optional string property.
+func getString(obj iscn.IscnObject, key string) (string, error) {
+	val, err := GetString(obj, key)
+	return val, optional(err)
+}
+
+// getCid reads an optional CID property.
+func getCid(obj iscn.IscnObject, key string) (cid.Cid, error) {
+	val, err := GetCid(obj, key)
+	if err != nil {
+		return cid.Undef, optional(err)
+	}
+	return val, nil
+}
+
+// Link is a property which is either a CID or a URL.
+type Link struct {
+	Cid cid.Cid
+	URL string
+}
+
+// Defined tells whether the link is set.
+func (l Link) Defined() bool {
+	return l.Cid.Defined() || len(l.URL) > 0
+}
+
+func (l Link) value() interface{} {
+	if l.Cid.Defined() {
+		return l.Cid
+	}
+	return l.URL
+}
+
+// GetLink reads a property which is either a CID or a URL. The error is a
+// *PathError.
+func GetLink(obj iscn.IscnObject, key string) (Link, error) {
+	c, url, err := obj.GetLink(key)
+	if err != nil {
+		return Link{}, getterError(err, key)
+	}
+	return Link{Cid: c, URL: url}, nil
+}
+
+// LookupLink reads an optional link property.
+func LookupLink(obj iscn.IscnObject, key string) (val Link, ok bool) {
+	val, err := GetLink(obj, key)
+	return val, err == nil
+}
+
+// getLink reads an optional link property.
+func getLink(obj iscn.IscnObject, key string) (Link, error) {
+	val, err := GetLink(obj, key)
+	return val, optional(err)
+}
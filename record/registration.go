@@ -0,0 +1,352 @@
+package record
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+
+	ipld "github.com/ipfs/go-ipld-format"
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// The kinds of the blocks in a manifest.
+const (
+	KindEntity       = "entity"
+	KindRights       = "rights"
+	KindStakeholders = "stakeholders"
+	KindContent      = "content"
+	KindKernel       = "kernel"
+)
+
+// ManifestEntry is a block created for a registration.
+type ManifestEntry struct {
+	// Kind is one of KindEntity, KindRights, KindStakeholders, KindContent and
+	// KindKernel.
+	Kind string
+
+	// Ref is the reference of an entity or the index of a content version.
+	Ref string
+
+	Cid cid.Cid
+}
+
+// Manifest lists every block created for a registration in dependency order.
+type Manifest struct {
+	Kernel cid.Cid
+	Blocks []ManifestEntry
+
+	objects []iscn.IscnObject
+}
+
+// Objects returns the blocks of the manifest in dependency order.
+func (m *Manifest) Objects() []iscn.IscnObject {
+	return m.objects
+}
+
+func (m *Manifest) add(kind string, ref string, obj iscn.IscnObject) {
+	m.Blocks = append(m.Blocks, ManifestEntry{
+		Kind: kind,
+		Ref:  ref,
+		Cid:  obj.Cid(),
+	})
+	m.objects = append(m.objects, obj)
+}
+
+type entityRef struct {
+	ref    string
+	entity Entity
+}
+
+type rightRef struct {
+	holder string
+	right  Right
+}
+
+type stakeholderRef struct {
+	stakeholder string
+	data        Stakeholder
+}
+
+// Registration assembles the blocks of a complete ISCN registration. The
+// entities are added with a reference which the rights and the stakeholders
+// use to link to them. Errors are kept until Build or Register is called.
+type Registration struct {
+	entities     []entityRef
+	rights       []rightRef
+	stakeholders []stakeholderRef
+	contents     []Content
+	kernel       Kernel
+
+	errs []string
+}
+
+// NewRegistration creates an empty registration.
+func NewRegistration() *Registration {
+	return &Registration{}
+}
+
+func (r *Registration) fail(format string, args ...interface{}) *Registration {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+	return r
+}
+
+func (r *Registration) hasEntity(ref string) bool {
+	for _, e := range r.entities {
+		if e.ref == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// Entity adds an entity with the reference ref.
+func (r *Registration) Entity(ref string, entity Entity) *Registration {
+	if len(ref) == 0 {
+		return r.fail("entity reference is empty")
+	}
+	if r.hasEntity(ref) {
+		return r.fail("entity %q is added twice", ref)
+	}
+
+	r.entities = append(r.entities, entityRef{ref: ref, entity: entity})
+	return r
+}
+
+// Right adds a right held by the entity of the reference holder. An empty
+// holder keeps the holder CID of the right, e.g. for an existing entity.
+func (r *Registration) Right(holder string, right Right) *Registration {
+	r.rights = append(r.rights, rightRef{holder: holder, right: right})
+	return r
+}
+
+// Stakeholder adds a stakeholder for the entity of the reference stakeholder.
+// An empty stakeholder keeps the stakeholder CID, e.g. for an existing entity.
+func (r *Registration) Stakeholder(
+	stakeholder string,
+	data Stakeholder,
+) *Registration {
+	r.stakeholders = append(r.stakeholders, stakeholderRef{
+		stakeholder: stakeholder,
+		data:        data,
+	})
+	return r
+}
+
+// Content adds a content version. Each version without a parent gets the
+// previous version as its parent and the kernel links to the last version.
+func (r *Registration) Content(content Content) *Registration {
+	r.contents = append(r.contents, content)
+	return r
+}
+
+// Kernel sets the ID, timestamp, version and custom properties of the kernel,
+// its links are filled in by Build.
+func (r *Registration) Kernel(kernel Kernel) *Registration {
+	r.kernel = kernel
+	return r
+}
+
+// check checks the cross-references of the registration.
+func (r *Registration) check() error {
+	errs := append([]string{}, r.errs...)
+	used := map[string]bool{}
+
+	for i, right := range r.rights {
+		if len(right.holder) == 0 {
+			if !right.right.Holder.Defined() {
+				errs = append(errs, fmt.Sprintf("right %d has no holder", i))
+			}
+			continue
+		}
+		if !r.hasEntity(right.holder) {
+			errs = append(errs, fmt.Sprintf(
+				"holder %q of right %d is not added",
+				right.holder,
+				i,
+			))
+		}
+		used[right.holder] = true
+	}
+
+	for i, s := range r.stakeholders {
+		if len(s.stakeholder) == 0 {
+			if !s.data.Stakeholder.Defined() {
+				errs = append(errs, fmt.Sprintf("stakeholder %d has no entity", i))
+			}
+			continue
+		}
+		if !r.hasEntity(s.stakeholder) {
+			errs = append(errs, fmt.Sprintf(
+				"entity %q of stakeholder %d is not added",
+				s.stakeholder,
+				i,
+			))
+		}
+		used[s.stakeholder] = true
+	}
+
+	for _, e := range r.entities {
+		if !used[e.ref] {
+			errs = append(errs, fmt.Sprintf("entity %q is not referenced", e.ref))
+		}
+	}
+
+	if len(r.contents) == 0 {
+		errs = append(errs, "no content is added")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid registration: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// validate checks the semantics of every block before anything is encoded,
+// the paths of the errors are prefixed with the kind of the block, e.g.
+// "content/1/version".
+func (r *Registration) validate() error {
+	errs := ValidationErrors{}
+
+	for _, e := range r.entities {
+		errs.prefix(KindEntity+"/"+e.ref, e.entity.validate())
+	}
+
+	for i, right := range r.rights {
+		nested := right.right.validate()
+		if len(right.holder) > 0 {
+			nested = nested.without("holder")
+		}
+		errs.prefix(fmt.Sprintf("%s/rights/%d", KindRights, i), nested)
+	}
+
+	for i, s := range r.stakeholders {
+		nested := s.data.validate()
+		if len(s.stakeholder) > 0 {
+			nested = nested.without("stakeholder")
+		}
+		errs.prefix(fmt.Sprintf("%s/stakeholders/%d", KindStakeholders, i), nested)
+	}
+
+	var parent *Content
+	for i := range r.contents {
+		content := &r.contents[i]
+		if content.Parent.Defined() {
+			// The explicit parent is not a part of the registration
+			parent = nil
+		}
+		errs.prefix(fmt.Sprintf("%s/%d", KindContent, i), content.validate(parent))
+		parent = content
+	}
+
+	errs.prefix(KindKernel, r.kernel.validate())
+
+	return errs.err()
+}
+
+// Build checks the cross-references and the semantics of the blocks, then
+// encodes the blocks in dependency order: the entities, the rights and the
+// stakeholders, the content versions and at last the kernel. The error is a
+// ValidationErrors if a block is invalid.
+func (r *Registration) Build() (*Manifest, error) {
+	if err := r.check(); err != nil {
+		return nil, err
+	}
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+	entities := map[string]cid.Cid{}
+
+	for _, e := range r.entities {
+		obj, err := e.entity.Encode()
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode entity %q: %s", e.ref, err)
+		}
+		entities[e.ref] = obj.Cid()
+		m.add(KindEntity, e.ref, obj)
+	}
+
+	rights := Rights{}
+	for _, right := range r.rights {
+		data := right.right
+		if len(right.holder) > 0 {
+			data.Holder = entities[right.holder]
+		}
+		rights.Rights = append(rights.Rights, data)
+	}
+	rightsObj, err := rights.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode rights: %s", err)
+	}
+	m.add(KindRights, "", rightsObj)
+
+	stakeholders := Stakeholders{}
+	for _, s := range r.stakeholders {
+		data := s.data
+		if len(s.stakeholder) > 0 {
+			data.Stakeholder = entities[s.stakeholder]
+		}
+		stakeholders.Stakeholders = append(stakeholders.Stakeholders, data)
+	}
+	stakeholdersObj, err := stakeholders.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode stakeholders: %s", err)
+	}
+	m.add(KindStakeholders, "", stakeholdersObj)
+
+	parent := cid.Undef
+	for i, content := range r.contents {
+		if !content.Parent.Defined() {
+			content.Parent = parent
+		}
+
+		obj, err := content.Encode()
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode content %d: %s", i, err)
+		}
+		parent = obj.Cid()
+		m.add(KindContent, strconv.Itoa(i), obj)
+	}
+
+	kernel := r.kernel
+	kernel.Rights = rightsObj.Cid()
+	kernel.Stakeholders = stakeholdersObj.Cid()
+	kernel.Content = parent
+	kernelObj, err := kernel.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode kernel: %s", err)
+	}
+	m.add(KindKernel, "", kernelObj)
+	m.Kernel = kernelObj.Cid()
+
+	return m, nil
+}
+
+// Register builds the registration and pins all its blocks with the adder,
+// e.g. ipfs.Dag().Pinning(). Nothing is pinned if the registration is invalid.
+func (r *Registration) Register(
+	ctx context.Context,
+	adder ipld.NodeAdder,
+) (*Manifest, error) {
+	m, err := r.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, obj := range m.objects {
+		if err := adder.Add(ctx, obj); err != nil {
+			return nil, fmt.Errorf(
+				"cannot pin %s block %s: %s",
+				m.Blocks[i].Kind,
+				obj.Cid(),
+				err,
+			)
+		}
+	}
+
+	return m, nil
+}
@@ -0,0 +1,128 @@
+package record
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// demoRegistration assembles a registration like the one of the demo.
+func demoRegistration(t *testing.T) *Registration {
+	t.Helper()
+
+	terms, err := (&Entity{ID: "lcc://id/terms"}).Encode()
+	if err != nil {
+		t.Fatalf("cannot encode terms: %s", err)
+	}
+
+	id := make([]byte, KernelIDLength)
+	for i := range id {
+		id[i] = byte(i)
+	}
+
+	return NewRegistration().
+		Entity("alice", Entity{ID: "lcc://id/alice", Name: "Alice"}).
+		Entity("bob", Entity{ID: "lcc://id/bob"}).
+		Right("alice", Right{Type: "license", Terms: terms.Cid()}).
+		Stakeholder("alice", Stakeholder{Type: "Creator", Sharing: 8}).
+		Stakeholder("bob", Stakeholder{Type: "Contributor", Sharing: 2}).
+		Content(Content{Type: "article", Version: 1, Fingerprint: "fp", Title: "Hello"}).
+		Content(Content{Type: "article", Version: 2, Fingerprint: "fp", Title: "Hello"}).
+		Kernel(Kernel{
+			ID:        id,
+			Timestamp: time.Date(2020, 1, 1, 12, 34, 56, 0, time.UTC),
+			Version:   1,
+		})
+}
+
+func TestRegistrationDanglingReference(t *testing.T) {
+	_, err := demoRegistration(t).
+		Right("carol", Right{Type: "license", Terms: demoBlocks(t)[4].Cid()}).
+		Build()
+	if err == nil || !strings.Contains(err.Error(), `holder "carol" of right 1 is not added`) {
+		t.Errorf("Build() = %v, want the holder not added", err)
+	}
+}
+
+func TestRegistrationUnreferencedEntity(t *testing.T) {
+	_, err := demoRegistration(t).
+		Entity("carol", Entity{ID: "lcc://id/carol"}).
+		Build()
+	if err == nil || !strings.Contains(err.Error(), `entity "carol" is not referenced`) {
+		t.Errorf("Build() = %v, want the entity not referenced", err)
+	}
+}
+
+func TestRegistrationBuild(t *testing.T) {
+	m, err := demoRegistration(t).Build()
+	if err != nil {
+		t.Fatalf("Build() = %s", err)
+	}
+
+	want := []struct {
+		kind string
+		ref  string
+	}{
+		{KindEntity, "alice"},
+		{KindEntity, "bob"},
+		{KindRights, ""},
+		{KindStakeholders, ""},
+		{KindContent, "0"},
+		{KindContent, "1"},
+		{KindKernel, ""},
+	}
+	if len(m.Blocks) != len(want) {
+		t.Fatalf("manifest has %d blocks, want %d", len(m.Blocks), len(want))
+	}
+	for i, w := range want {
+		if m.Blocks[i].Kind != w.kind || m.Blocks[i].Ref != w.ref {
+			t.Errorf(
+				"block %d is %s %q, want %s %q",
+				i,
+				m.Blocks[i].Kind,
+				m.Blocks[i].Ref,
+				w.kind,
+				w.ref,
+			)
+		}
+	}
+
+	objs := m.Objects()
+	if len(objs) != len(m.Blocks) {
+		t.Fatalf("manifest has %d objects, want %d", len(objs), len(m.Blocks))
+	}
+	for i, obj := range objs {
+		if !obj.Cid().Equals(m.Blocks[i].Cid) {
+			t.Errorf("object %d is %s, want %s", i, obj.Cid(), m.Blocks[i].Cid)
+		}
+	}
+	if !m.Kernel.Equals(m.Blocks[len(m.Blocks)-1].Cid) {
+		t.Errorf("kernel is %s, want %s", m.Kernel, m.Blocks[len(m.Blocks)-1].Cid)
+	}
+
+	kernel := Kernel{}
+	if err := kernel.FromObject(objs[6]); err != nil {
+		t.Fatalf("cannot read kernel: %s", err)
+	}
+	if !kernel.Rights.Equals(m.Blocks[2].Cid) ||
+		!kernel.Stakeholders.Equals(m.Blocks[3].Cid) ||
+		!kernel.Content.Equals(m.Blocks[5].Cid) {
+		t.Errorf("kernel links to %s, %s and %s", kernel.Rights, kernel.Stakeholders, kernel.Content)
+	}
+
+	rights := Rights{}
+	if err := rights.FromObject(objs[2]); err != nil {
+		t.Fatalf("cannot read rights: %s", err)
+	}
+	if !rights.Rights[0].Holder.Equals(m.Blocks[0].Cid) {
+		t.Errorf("holder is %s, want alice %s", rights.Rights[0].Holder, m.Blocks[0].Cid)
+	}
+
+	content := Content{}
+	if err := content.FromObject(objs[5]); err != nil {
+		t.Fatalf("cannot read content: %s", err)
+	}
+	if !content.Parent.Equals(m.Blocks[4].Cid) {
+		t.Errorf("parent is %s, want %s", content.Parent, m.Blocks[4].Cid)
+	}
+}
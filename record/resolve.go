@@ -0,0 +1,178 @@
+package record
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/ipfs/go-cid"
+
+	ipld "github.com/ipfs/go-ipld-format"
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// ResolvedCidKey is the key of the CID of an inlined block in a resolved
+// document.
+const ResolvedCidKey = "@cid"
+
+// ResolveProblem is a link which is not inlined in a resolved document.
+type ResolveProblem struct {
+	// Path of the link in the document, e.g. "rights/rights/1/holder".
+	Path    string
+	Cid     cid.Cid
+	Message string
+}
+
+func (p ResolveProblem) Error() string {
+	return fmt.Sprintf("%s (%s): %s", p.Path, p.Cid, p.Message)
+}
+
+// Resolved is a kernel with the blocks it links to inlined.
+type Resolved struct {
+	// Document is the data of the kernel, the links to the ISCN blocks are
+	// replaced by the data of the blocks with their CIDs under
+	// ResolvedCidKey. The footprints, which link to other registrations, and
+	// the other links, e.g. the terms, stay as CIDs.
+	Document map[string]interface{}
+
+	// Problems are the cycles and the blocks which cannot be fetched, their
+	// links stay as CIDs.
+	Problems []ResolveProblem
+}
+
+// MarshalJSON writes the document, the links are written as {"/": "<cid>"}.
+func (r *Resolved) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Document)
+}
+
+func joinPath(path string, name string) string {
+	if len(path) == 0 {
+		return name
+	}
+	return path + "/" + name
+}
+
+type resolver struct {
+	ctx      context.Context
+	getter   ipld.NodeGetter
+	problems []ResolveProblem
+
+	// visiting are the blocks being resolved, to detect the cycles
+	visiting map[cid.Cid]bool
+}
+
+// ResolveKernel fetches the kernel and the ISCN blocks it links to with the
+// getter, and inlines them into one document. The blocks up to depth
+// links away from the kernel are inlined, a negative depth has no limit. The
+// cycles and the missing blocks are reported in the problems, only a kernel
+// which cannot be fetched is an error. The getter should bound the time to
+// fetch each block from the network, so a missing block is reported instead
+// of blocking.
+func ResolveKernel(
+	ctx context.Context,
+	getter ipld.NodeGetter,
+	c cid.Cid,
+	depth int,
+) (*Resolved, error) {
+	if c.Type() != iscn.CodecISCN {
+		return nil, &PathError{
+			Err:    ErrInvalidCodec,
+			Detail: fmt.Sprintf("block %s is not an ISCN kernel", c),
+		}
+	}
+
+	r := &resolver{
+		ctx:      ctx,
+		getter:   getter,
+		visiting: map[cid.Cid]bool{},
+	}
+
+	doc, err := r.resolveBlock("", c, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Resolved{Document: doc, Problems: r.problems}, nil
+}
+
+func (r *resolver) fetch(c cid.Cid) (map[string]interface{}, error) {
+	nd, err := r.getter.Get(r.ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch block: %s", err)
+	}
+	obj, err := iscn.Decode(nd.RawData(), c)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode block: %s", err)
+	}
+
+	return blockData(obj)
+}
+
+// resolveBlock resolves the block of the CID at the path of the document.
+func (r *resolver) resolveBlock(
+	path string,
+	c cid.Cid,
+	depth int,
+) (map[string]interface{}, error) {
+	data, err := r.fetch(c)
+	if err != nil {
+		return nil, err
+	}
+
+	r.visiting[c] = true
+	defer delete(r.visiting, c)
+
+	r.resolveValue(path, data, depth)
+	data[ResolvedCidKey] = c
+
+	return data, nil
+}
+
+func (r *resolver) resolveValue(path string, value interface{}, depth int) interface{} {
+	switch v := value.(type) {
+	case cid.Cid:
+		if !IsISCN(v) || depth == 0 {
+			return v
+		}
+		if r.visiting[v] {
+			r.problems = append(r.problems, ResolveProblem{
+				Path:    path,
+				Cid:     v,
+				Message: "cycle",
+			})
+			return v
+		}
+
+		doc, err := r.resolveBlock(path, v, depth-1)
+		if err != nil {
+			r.problems = append(r.problems, ResolveProblem{
+				Path:    path,
+				Cid:     v,
+				Message: err.Error(),
+			})
+			return v
+		}
+		return doc
+	case map[string]interface{}:
+		for key, val := range v {
+			// The footprints link to other registrations
+			if key != "footprint" {
+				v[key] = r.resolveValue(joinPath(path, key), val, depth)
+			}
+		}
+		return v
+	case []map[string]interface{}:
+		for i, val := range v {
+			r.resolveValue(joinPath(path, strconv.Itoa(i)), val, depth)
+		}
+		return v
+	case []interface{}:
+		for i, val := range v {
+			v[i] = r.resolveValue(joinPath(path, strconv.Itoa(i)), val, depth)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,57 @@
+package record
+
+import (
+	"context"
+	"testing"
+
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+func TestResolveKernelKeepsFootprints(t *testing.T) {
+	objs := demoBlocks(t)
+	kernel, rights, content, entity := objs[0], objs[1], objs[3], objs[4]
+
+	// The footprint links to a kernel of another registration, which is not
+	// in the getter
+	footprint := kernel.Cid()
+	stakeholders, err := (&Stakeholders{
+		Stakeholders: []Stakeholder{
+			{
+				Type:        "Creator",
+				Stakeholder: entity.Cid(),
+				Sharing:     1,
+				Footprint:   Link{Cid: footprint},
+			},
+		},
+	}).Encode()
+	if err != nil {
+		t.Fatalf("cannot encode stakeholders: %s", err)
+	}
+	k := Kernel{}
+	if err := k.FromObject(kernel); err != nil {
+		t.Fatalf("cannot read kernel: %s", err)
+	}
+	k.Stakeholders = stakeholders.Cid()
+	kernel, err = k.Encode()
+	if err != nil {
+		t.Fatalf("cannot encode kernel: %s", err)
+	}
+
+	getter := newMemGetter([]iscn.IscnObject{kernel, rights, stakeholders, content, entity})
+	resolved, err := ResolveKernel(context.Background(), getter, kernel.Cid(), -1)
+	if err != nil {
+		t.Fatalf("ResolveKernel() = %s", err)
+	}
+	if len(resolved.Problems) != 0 {
+		t.Errorf("ResolveKernel() reports %v, want no problem", resolved.Problems)
+	}
+
+	doc := resolved.Document["stakeholders"].(map[string]interface{})
+	stakeholder := doc["stakeholders"].([]map[string]interface{})[0]
+	if got := stakeholder["footprint"]; got != footprint {
+		t.Errorf("footprint is %v, want the CID %s", got, footprint)
+	}
+	if _, ok := stakeholder["stakeholder"].(map[string]interface{}); !ok {
+		t.Errorf("stakeholder %v is not inlined", stakeholder["stakeholder"])
+	}
+}
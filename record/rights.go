@@ -0,0 +1,156 @@
+package record
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// Period is the time period of a right, both ends are optional and are zero
+// if not set.
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+func (p *Period) toMap() map[string]interface{} {
+	data := map[string]interface{}{}
+	if !p.From.IsZero() {
+		putTime(data, "from", p.From)
+	}
+	if !p.To.IsZero() {
+		putTime(data, "to", p.To)
+	}
+	return data
+}
+
+// FromObject reads the period from the period object of a right.
+func (p *Period) FromObject(obj iscn.IscnObject) error {
+	return p.fromObject(obj).first()
+}
+
+func (p *Period) fromObject(obj iscn.IscnObject) decodeErrors {
+	errs := decodeErrors{}
+
+	var err error
+	p.From, err = getTime(obj, "from")
+	errs.add(err)
+	p.To, err = getTime(obj, "to")
+	errs.add(err)
+
+	return errs
+}
+
+// Right is a right of the content held by an entity.
+type Right struct {
+	Holder cid.Cid
+	Type   string
+	Terms  cid.Cid
+
+	// Optional properties
+	Period    *Period
+	Territory string
+}
+
+func (r *Right) toMap() map[string]interface{} {
+	data := map[string]interface{}{
+		"holder": r.Holder,
+		"type":   r.Type,
+		"terms":  r.Terms,
+	}
+	if r.Period != nil {
+		data["period"] = r.Period.toMap()
+	}
+	if len(r.Territory) > 0 {
+		data["territory"] = r.Territory
+	}
+	return data
+}
+
+// FromObject reads the right from a right object of a rights block.
+func (r *Right) FromObject(obj iscn.IscnObject) error {
+	return r.fromObject(obj).first()
+}
+
+func (r *Right) fromObject(obj iscn.IscnObject) decodeErrors {
+	errs := decodeErrors{}
+
+	var err error
+	r.Holder, err = GetCid(obj, "holder")
+	errs.add(err)
+	r.Type, err = GetString(obj, "type")
+	errs.add(err)
+	r.Terms, err = GetCid(obj, "terms")
+	errs.add(err)
+
+	r.Period = nil
+	if period, err := GetObject(obj, "period"); err == nil {
+		r.Period = &Period{}
+		errs.nested("period", r.Period.fromObject(period))
+	} else {
+		errs.add(optional(err))
+	}
+
+	r.Territory, err = getString(obj, "territory")
+	errs.add(err)
+
+	return errs
+}
+
+// Rights is the list of the rights of a registration.
+type Rights struct {
+	Rights []Right
+}
+
+// Encode encodes the rights into an ISCN rights block.
+func (r *Rights) Encode() (iscn.IscnObject, error) {
+	rights := make([]map[string]interface{}, 0, len(r.Rights))
+	for i := range r.Rights {
+		rights = append(rights, r.Rights[i].toMap())
+	}
+
+	data := map[string]interface{}{
+		"rights": rights,
+	}
+
+	return iscn.Encode(iscn.CodecRights, SchemaVersion, data)
+}
+
+// FromObject reads the rights from an ISCN rights block.
+func (r *Rights) FromObject(obj iscn.IscnObject) error {
+	return r.fromObject(obj).first()
+}
+
+// fromObject reads the rights, the properties which cannot be read are left
+// zero.
+func (r *Rights) fromObject(obj iscn.IscnObject) decodeErrors {
+	errs := decodeErrors{}
+	if err := checkCodec(obj, iscn.CodecRights); err != nil {
+		return append(errs, err)
+	}
+
+	rights, err := GetArray(obj, "rights")
+	if err != nil {
+		return append(errs, err)
+	}
+
+	r.Rights = make([]Right, len(rights))
+	for i, right := range rights {
+		path := fmt.Sprintf("rights/%d", i)
+		o, ok := right.(iscn.IscnObject)
+		if !ok {
+			errs.add(&PathError{
+				Path:   path,
+				Err:    ErrTypeMismatch,
+				Detail: "right is not an object",
+			})
+			continue
+		}
+		errs.nested(path, r.Rights[i].fromObject(o))
+	}
+
+	return errs
+}
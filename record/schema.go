@@ -0,0 +1,328 @@
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// JSONSchemaDialect is the JSON Schema draft of the exported schemas.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// schemas are the JSON Schema properties of each codec keyed by the schema
+// version.
+var schemas = map[uint64]map[uint64]func() jsonSchema{
+	1: {
+		iscn.CodecISCN:         kernelSchemaV1,
+		iscn.CodecContent:      contentSchemaV1,
+		iscn.CodecEntity:       entitySchemaV1,
+		iscn.CodecRights:       rightsSchemaV1,
+		iscn.CodecStakeholders: stakeholdersSchemaV1,
+	},
+	2: {
+		iscn.CodecISCN:         kernelSchemaV2,
+		iscn.CodecContent:      contentSchemaV1,
+		iscn.CodecEntity:       entitySchemaV1,
+		iscn.CodecRights:       rightsSchemaV2,
+		iscn.CodecStakeholders: stakeholdersSchemaV1,
+	},
+}
+
+type jsonSchema = map[string]interface{}
+
+// schemaDefs returns the definitions shared by the schemas, the links are
+// written as in the output of MarshalJSON.
+func schemaDefs() jsonSchema {
+	return jsonSchema{
+		"cid": jsonSchema{
+			"description": "An IPLD link",
+			"type":        "object",
+			"properties": jsonSchema{
+				"/": jsonSchema{"type": "string"},
+			},
+			"required":             []string{"/"},
+			"additionalProperties": false,
+		},
+		"link": jsonSchema{
+			"description": "Either an IPLD link or a URL",
+			"oneOf": []jsonSchema{
+				{"$ref": "#/$defs/cid"},
+				{"type": "string", "format": "uri"},
+			},
+		},
+		"timestamp": jsonSchema{
+			"description": "An RFC 3339 timestamp",
+			"type":        "string",
+			"format":      "date-time",
+		},
+		"utcTimestamp": jsonSchema{
+			"description": "An RFC 3339 timestamp in UTC",
+			"type":        "string",
+			"format":      "date-time",
+			"pattern":     "Z$",
+		},
+		"offset": jsonSchema{
+			"description": "The original offset of a timestamp",
+			"type":        "string",
+			"pattern":     "^[+-][0-9]{2}:[0-9]{2}$",
+		},
+	}
+}
+
+func ref(def string) jsonSchema {
+	return jsonSchema{"$ref": "#/$defs/" + def}
+}
+
+func str() jsonSchema {
+	return jsonSchema{"type": "string"}
+}
+
+func requiredStr() jsonSchema {
+	return jsonSchema{"type": "string", "minLength": 1}
+}
+
+func integer(min uint64, max uint64) jsonSchema {
+	return jsonSchema{"type": "integer", "minimum": min, "maximum": max}
+}
+
+func object(
+	properties jsonSchema,
+	required []string,
+	additional bool,
+) jsonSchema {
+	return jsonSchema{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": additional,
+	}
+}
+
+// propertySchema returns the JSON Schema of a custom property.
+func propertySchema(property Property) jsonSchema {
+	switch property.Type {
+	case TypeInt:
+		return jsonSchema{"type": "integer"}
+	case TypeUint:
+		return jsonSchema{"type": "integer", "minimum": 0}
+	case TypeBytes:
+		return jsonSchema{"type": "string", "contentEncoding": "base64"}
+	case TypeCid:
+		return ref("cid")
+	case TypeStrings:
+		return jsonSchema{"type": "array", "items": str()}
+	case TypeObject:
+		properties := jsonSchema{}
+		required := []string{}
+		for name, nested := range property.Properties {
+			properties[name] = propertySchema(nested)
+			if nested.Required {
+				required = append(required, name)
+			}
+		}
+		sort.Strings(required)
+		return object(properties, required, false)
+	default:
+		return str()
+	}
+}
+
+// customSchema returns the JSON Schemas of the custom properties of the
+// registered namespaces.
+func customSchema() jsonSchema {
+	namespacesLock.RLock()
+	defer namespacesLock.RUnlock()
+
+	properties := jsonSchema{}
+	for namespace, declared := range namespaces {
+		for name, property := range declared {
+			properties[namespace+NamespaceSeparator+name] = propertySchema(property)
+		}
+	}
+	return properties
+}
+
+func kernelSchemaV1() jsonSchema {
+	properties := jsonSchema{
+		"id": jsonSchema{
+			"description":      "The 32-byte kernel ID",
+			"type":             "string",
+			"contentEncoding":  "base64",
+			"contentMediaType": "application/octet-stream",
+			"minLength":        44,
+			"maxLength":        44,
+		},
+		"timestamp":    ref("timestamp"),
+		"version":      integer(1, 1<<64-1),
+		"rights":       ref("cid"),
+		"stakeholders": ref("cid"),
+		"content":      ref("cid"),
+	}
+	for key, property := range customSchema() {
+		properties[key] = property
+	}
+
+	return object(
+		properties,
+		[]string{"id", "timestamp", "version", "rights", "stakeholders", "content"},
+		// The unregistered custom properties
+		true,
+	)
+}
+
+// kernelSchemaV2 stores the timestamp in UTC with its original offset.
+func kernelSchemaV2() jsonSchema {
+	schema := kernelSchemaV1()
+	properties := schema["properties"].(jsonSchema)
+	properties["timestamp"] = ref("utcTimestamp")
+	properties["timestampOffset"] = ref("offset")
+	return schema
+}
+
+func contentSchemaV1() jsonSchema {
+	return object(
+		jsonSchema{
+			"type":        requiredStr(),
+			"version":     integer(1, 1<<64-1),
+			"fingerprint": requiredStr(),
+			"title":       requiredStr(),
+			"parent": jsonSchema{
+				"oneOf": []jsonSchema{
+					ref("cid"),
+					{"type": "null"},
+				},
+			},
+			"source":      str(),
+			"edition":     str(),
+			"description": str(),
+			"tags": jsonSchema{
+				"type":  "array",
+				"items": str(),
+			},
+		},
+		[]string{"type", "version", "fingerprint", "title", "parent"},
+		false,
+	)
+}
+
+func entitySchemaV1() jsonSchema {
+	return object(
+		jsonSchema{
+			"id":          requiredStr(),
+			"name":        str(),
+			"description": str(),
+		},
+		[]string{"id"},
+		false,
+	)
+}
+
+func rightsSchemaV1() jsonSchema {
+	return rightsSchema(object(
+		jsonSchema{
+			"from": ref("timestamp"),
+			"to":   ref("timestamp"),
+		},
+		[]string{},
+		false,
+	))
+}
+
+// rightsSchemaV2 stores the period ends in UTC with their original offsets.
+func rightsSchemaV2() jsonSchema {
+	return rightsSchema(object(
+		jsonSchema{
+			"from":       ref("utcTimestamp"),
+			"fromOffset": ref("offset"),
+			"to":         ref("utcTimestamp"),
+			"toOffset":   ref("offset"),
+		},
+		[]string{},
+		false,
+	))
+}
+
+// rightsSchema returns the schema of the rights with the period schema.
+func rightsSchema(period jsonSchema) jsonSchema {
+	right := object(
+		jsonSchema{
+			"holder":    ref("cid"),
+			"type":      requiredStr(),
+			"terms":     ref("cid"),
+			"period":    period,
+			"territory": str(),
+		},
+		[]string{"holder", "type", "terms"},
+		false,
+	)
+
+	return object(
+		jsonSchema{
+			"rights": jsonSchema{
+				"type":  "array",
+				"items": right,
+			},
+		},
+		[]string{"rights"},
+		false,
+	)
+}
+
+func stakeholdersSchemaV1() jsonSchema {
+	stakeholder := object(
+		jsonSchema{
+			"type":        requiredStr(),
+			"stakeholder": ref("cid"),
+			"sharing":     integer(1, 1<<32-1),
+			"footprint":   ref("link"),
+		},
+		[]string{"type", "stakeholder", "sharing"},
+		false,
+	)
+
+	return object(
+		jsonSchema{
+			"stakeholders": jsonSchema{
+				"type":  "array",
+				"items": stakeholder,
+			},
+		},
+		[]string{"stakeholders"},
+		false,
+	)
+}
+
+// JSONSchema returns the JSON Schema (draft 2020-12) of the blocks of the
+// codec and schema version, as written by MarshalJSON.
+func JSONSchema(codec uint64, schemaVersion uint64) (map[string]interface{}, error) {
+	versioned, ok := schemas[schemaVersion]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema version %d", schemaVersion)
+	}
+	properties, ok := versioned[codec]
+	if !ok {
+		return nil, fmt.Errorf("unknown codec 0x%x", codec)
+	}
+
+	schema := properties()
+	schema["$schema"] = JSONSchemaDialect
+	schema["title"] = fmt.Sprintf(
+		"ISCN %s (schema version %d)",
+		codecNames[codec],
+		schemaVersion,
+	)
+	schema["$defs"] = schemaDefs()
+
+	return schema, nil
+}
+
+// MarshalJSONSchema returns the indented JSON of JSONSchema.
+func MarshalJSONSchema(codec uint64, schemaVersion uint64) ([]byte, error) {
+	schema, err := JSONSchema(codec, schemaVersion)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
@@ -0,0 +1,108 @@
+package record
+
+import (
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// Stakeholder is an entity sharing the benefit of the content.
+type Stakeholder struct {
+	Type        string
+	Stakeholder cid.Cid
+	Sharing     uint32
+
+	// Footprint is optional, it is either a CID or a URL.
+	Footprint Link
+}
+
+func (s *Stakeholder) toMap() map[string]interface{} {
+	data := map[string]interface{}{
+		"type":        s.Type,
+		"stakeholder": s.Stakeholder,
+		"sharing":     s.Sharing,
+	}
+	if s.Footprint.Defined() {
+		data["footprint"] = s.Footprint.value()
+	}
+	return data
+}
+
+// FromObject reads the stakeholder from a stakeholder object of a
+// stakeholders block.
+func (s *Stakeholder) FromObject(obj iscn.IscnObject) error {
+	return s.fromObject(obj).first()
+}
+
+func (s *Stakeholder) fromObject(obj iscn.IscnObject) decodeErrors {
+	errs := decodeErrors{}
+
+	var err error
+	s.Type, err = GetString(obj, "type")
+	errs.add(err)
+	s.Stakeholder, err = GetCid(obj, "stakeholder")
+	errs.add(err)
+	s.Sharing, err = GetUint32(obj, "sharing")
+	errs.add(err)
+	s.Footprint, err = getLink(obj, "footprint")
+	errs.add(err)
+
+	return errs
+}
+
+// Stakeholders is the list of the stakeholders of a registration.
+type Stakeholders struct {
+	Stakeholders []Stakeholder
+}
+
+// Encode encodes the stakeholders into an ISCN stakeholders block.
+func (s *Stakeholders) Encode() (iscn.IscnObject, error) {
+	stakeholders := make([]map[string]interface{}, 0, len(s.Stakeholders))
+	for i := range s.Stakeholders {
+		stakeholders = append(stakeholders, s.Stakeholders[i].toMap())
+	}
+
+	data := map[string]interface{}{
+		"stakeholders": stakeholders,
+	}
+
+	return iscn.Encode(iscn.CodecStakeholders, SchemaVersion, data)
+}
+
+// FromObject reads the stakeholders from an ISCN stakeholders block.
+func (s *Stakeholders) FromObject(obj iscn.IscnObject) error {
+	return s.fromObject(obj).first()
+}
+
+// fromObject reads the stakeholders, the properties which cannot be read are
+// left zero.
+func (s *Stakeholders) fromObject(obj iscn.IscnObject) decodeErrors {
+	errs := decodeErrors{}
+	if err := checkCodec(obj, iscn.CodecStakeholders); err != nil {
+		return append(errs, err)
+	}
+
+	stakeholders, err := GetArray(obj, "stakeholders")
+	if err != nil {
+		return append(errs, err)
+	}
+
+	s.Stakeholders = make([]Stakeholder, len(stakeholders))
+	for i, stakeholder := range stakeholders {
+		path := fmt.Sprintf("stakeholders/%d", i)
+		o, ok := stakeholder.(iscn.IscnObject)
+		if !ok {
+			errs.add(&PathError{
+				Path:   path,
+				Err:    ErrTypeMismatch,
+				Detail: "stakeholder is not an object",
+			})
+			continue
+		}
+		errs.nested(path, s.Stakeholders[i].fromObject(o))
+	}
+
+	return errs
+}
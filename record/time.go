@@ -0,0 +1,136 @@
+package record
+
+import (
+	"fmt"
+	"time"
+
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// OffsetSuffix is the suffix of the property keeping the original offset of
+// a timestamp since schema version 2, e.g. "timestampOffset" for "timestamp".
+const OffsetSuffix = "Offset"
+
+// offsetLayout is the layout of the original offset, e.g. "+08:00".
+const offsetLayout = "-07:00"
+
+// putTime stores the timestamp in RFC 3339 in UTC, so the timestamps compare
+// the same whatever their time zones are. The original offset is stored along
+// if it is not UTC.
+func putTime(data map[string]interface{}, key string, t time.Time) {
+	data[key] = t.UTC().Format(time.RFC3339Nano)
+	if _, offset := t.Zone(); offset != 0 {
+		data[key+OffsetSuffix] = t.Format(offsetLayout)
+	}
+}
+
+// ParseTime parses an RFC 3339 timestamp.
+func ParseTime(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// GetTime reads an RFC 3339 timestamp property. The timestamp is in its
+// original offset if it is kept, otherwise it is as stored, e.g. in the
+// offset of a schema version 1 block. The error is a *PathError.
+func GetTime(obj iscn.IscnObject, key string) (time.Time, error) {
+	value, err := GetString(obj, key)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t, err := ParseTime(value)
+	if err != nil {
+		return time.Time{}, &PathError{
+			Path:   key,
+			Err:    ErrTypeMismatch,
+			Detail: fmt.Sprintf("%q is not an RFC 3339 timestamp", value),
+		}
+	}
+
+	offsetKey := key + OffsetSuffix
+	offset, err := GetString(obj, offsetKey)
+	if err = optional(err); err != nil {
+		return time.Time{}, err
+	}
+	if len(offset) == 0 {
+		return t, nil
+	}
+
+	zone, err := time.Parse(offsetLayout, offset)
+	if err != nil {
+		return time.Time{}, &PathError{
+			Path:   offsetKey,
+			Err:    ErrTypeMismatch,
+			Detail: fmt.Sprintf("%q is not an offset", offset),
+		}
+	}
+	_, seconds := zone.Zone()
+	return t.In(time.FixedZone(offset, seconds)), nil
+}
+
+// LookupTime reads an optional timestamp property.
+func LookupTime(obj iscn.IscnObject, key string) (val time.Time, ok bool) {
+	val, err := GetTime(obj, key)
+	return val, err == nil
+}
+
+// getTime reads an optional timestamp property, it is zero if missing.
+func getTime(obj iscn.IscnObject, key string) (time.Time, error) {
+	val, err := GetTime(obj, key)
+	return val, optional(err)
+}
+
+// upgradeTime rewrites a timestamp of schema version 1, which is stored in
+// its own offset, in UTC with its original offset.
+func upgradeTime(data map[string]interface{}, key string) error {
+	value, ok := data[key]
+	if !ok {
+		return nil
+	}
+	str, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%q: %T is not a timestamp", key, value)
+	}
+	t, err := ParseTime(str)
+	if err != nil {
+		return fmt.Errorf("%q: %q is not an RFC 3339 timestamp", key, str)
+	}
+
+	delete(data, key+OffsetSuffix)
+	putTime(data, key, t)
+	return nil
+}
+
+func upgradeKernelV1(data map[string]interface{}) (map[string]interface{}, error) {
+	return data, upgradeTime(data, "timestamp")
+}
+
+func upgradeRightsV1(data map[string]interface{}) (map[string]interface{}, error) {
+	rights, _ := data["rights"].([]map[string]interface{})
+	for i, right := range rights {
+		period, ok := right["period"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range []string{"from", "to"} {
+			if err := upgradeTime(period, key); err != nil {
+				return nil, fmt.Errorf("rights %d period: %s", i, err)
+			}
+		}
+	}
+	return data, nil
+}
+
+// keepData is the upgrader of the codecs without a timestamp.
+func keepData(data map[string]interface{}) (map[string]interface{}, error) {
+	return data, nil
+}
+
+// Schema version 2 stores the timestamps in UTC with their original offsets.
+func init() {
+	RegisterUpgrader(iscn.CodecISCN, 1, upgradeKernelV1)
+	RegisterUpgrader(iscn.CodecRights, 1, upgradeRightsV1)
+	RegisterUpgrader(iscn.CodecContent, 1, keepData)
+	RegisterUpgrader(iscn.CodecEntity, 1, keepData)
+	RegisterUpgrader(iscn.CodecStakeholders, 1, keepData)
+}
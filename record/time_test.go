@@ -0,0 +1,57 @@
+package record
+
+import (
+	"testing"
+	"time"
+
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+func TestUpgradeTimestamps(t *testing.T) {
+	kernel, err := upgradeData(iscn.CodecISCN, 1, 2, map[string]interface{}{
+		"timestamp": "2046-01-01T12:34:56+08:00",
+	})
+	if err != nil {
+		t.Fatalf("cannot upgrade kernel: %s", err)
+	}
+	if got := kernel["timestamp"]; got != "2046-01-01T04:34:56Z" {
+		t.Errorf("timestamp = %v, want 2046-01-01T04:34:56Z", got)
+	}
+	if got := kernel["timestampOffset"]; got != "+08:00" {
+		t.Errorf("timestampOffset = %v, want +08:00", got)
+	}
+
+	rights, err := upgradeData(iscn.CodecRights, 1, 2, map[string]interface{}{
+		"rights": []map[string]interface{}{
+			{"period": map[string]interface{}{"from": "2020-01-01T12:34:56Z"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("cannot upgrade rights: %s", err)
+	}
+	period := rights["rights"].([]map[string]interface{})[0]["period"].(map[string]interface{})
+	if got := period["from"]; got != "2020-01-01T12:34:56Z" {
+		t.Errorf("from = %v, want 2020-01-01T12:34:56Z", got)
+	}
+	if _, ok := period["fromOffset"]; ok {
+		t.Error("fromOffset is stored for a UTC timestamp")
+	}
+}
+
+func TestGetTimeKeepsOffset(t *testing.T) {
+	obj := demoBlocks(t)[1]
+
+	r := Rights{}
+	if err := r.FromObject(obj); err != nil {
+		t.Fatalf("cannot read rights: %s", err)
+	}
+
+	to := r.Rights[0].Period.To
+	if _, offset := to.Zone(); offset != 8*60*60 {
+		t.Errorf("offset of to = %d, want %d", offset, 8*60*60)
+	}
+	want := time.Date(2046, 1, 1, 4, 34, 56, 0, time.UTC)
+	if !to.Equal(want) {
+		t.Errorf("to = %s, want %s", to, want)
+	}
+}
@@ -0,0 +1,293 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	ipld "github.com/ipfs/go-ipld-format"
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+// KernelIDLength is the length of the kernel ID in bytes.
+const KernelIDLength = 32
+
+// FieldError is a validation error of a field, the path is relative to the
+// block, e.g. "rights/1/period/to".
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationErrors is the list of the field errors of a block.
+type ValidationErrors []FieldError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (errs *ValidationErrors) add(path string, format string, args ...interface{}) {
+	*errs = append(*errs, FieldError{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// prefix adds the errors of a nested object with the path prefix, an empty
+// path is the nested object itself.
+func (errs *ValidationErrors) prefix(prefix string, nested ValidationErrors) {
+	for _, err := range nested {
+		path := prefix
+		if len(err.Path) > 0 {
+			path += "/" + err.Path
+		}
+		*errs = append(*errs, FieldError{
+			Path:    path,
+			Message: err.Message,
+		})
+	}
+}
+
+// without drops the errors of a field, e.g. a link filled in by Build.
+func (errs ValidationErrors) without(path string) ValidationErrors {
+	kept := ValidationErrors{}
+	for _, err := range errs {
+		if err.Path != path {
+			kept = append(kept, err)
+		}
+	}
+	return kept
+}
+
+func (errs ValidationErrors) err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (errs *ValidationErrors) required(path string, value string) {
+	if len(value) == 0 {
+		errs.add(path, "is required")
+	}
+}
+
+func (k *Kernel) validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if len(k.ID) != KernelIDLength {
+		errs.add("id", "should be %d bytes, not %d", KernelIDLength, len(k.ID))
+	}
+	if k.Timestamp.IsZero() {
+		errs.add("timestamp", "is required")
+	}
+	if k.Version == 0 {
+		errs.add("version", "should be positive")
+	}
+	errs = append(errs, k.Custom.validate()...)
+
+	return errs
+}
+
+// Validate checks the semantics of the kernel.
+func (k *Kernel) Validate() error {
+	return k.validate().err()
+}
+
+func (e *Entity) validate() ValidationErrors {
+	errs := ValidationErrors{}
+	errs.required("id", e.ID)
+	return errs
+}
+
+// Validate checks the semantics of the entity.
+func (e *Entity) Validate() error {
+	return e.validate().err()
+}
+
+func (c *Content) validate(parent *Content) ValidationErrors {
+	errs := ValidationErrors{}
+
+	errs.required("type", c.Type)
+	errs.required("fingerprint", c.Fingerprint)
+	errs.required("title", c.Title)
+
+	if c.Version == 0 {
+		errs.add("version", "should be positive")
+	}
+	if parent != nil && c.Version <= parent.Version {
+		errs.add(
+			"version",
+			"%d does not increase over the version %d of the parent",
+			c.Version,
+			parent.Version,
+		)
+	}
+
+	return errs
+}
+
+// Validate checks the semantics of the content, the version is checked
+// against the parent if it is not nil.
+func (c *Content) Validate(parent *Content) error {
+	return c.validate(parent).err()
+}
+
+func (p *Period) validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if !p.From.IsZero() && !p.To.IsZero() && p.To.Before(p.From) {
+		errs.add(
+			"to",
+			"%s is earlier than %s",
+			p.To.Format(time.RFC3339),
+			p.From.Format(time.RFC3339),
+		)
+	}
+
+	return errs
+}
+
+func (r *Right) validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	if !r.Holder.Defined() {
+		errs.add("holder", "is required")
+	}
+	errs.required("type", r.Type)
+	if !r.Terms.Defined() {
+		errs.add("terms", "is required")
+	}
+	if r.Period != nil {
+		errs.prefix("period", r.Period.validate())
+	}
+
+	return errs
+}
+
+func (r *Rights) validate() ValidationErrors {
+	errs := ValidationErrors{}
+	for i := range r.Rights {
+		errs.prefix(fmt.Sprintf("rights/%d", i), r.Rights[i].validate())
+	}
+	return errs
+}
+
+// Validate checks the semantics of the rights.
+func (r *Rights) Validate() error {
+	return r.validate().err()
+}
+
+func (s *Stakeholder) validate() ValidationErrors {
+	errs := ValidationErrors{}
+
+	errs.required("type", s.Type)
+	if !s.Stakeholder.Defined() {
+		errs.add("stakeholder", "is required")
+	}
+	if s.Sharing == 0 {
+		errs.add("sharing", "should be positive")
+	}
+
+	return errs
+}
+
+func (s *Stakeholders) validate() ValidationErrors {
+	errs := ValidationErrors{}
+	for i := range s.Stakeholders {
+		errs.prefix(fmt.Sprintf("stakeholders/%d", i), s.Stakeholders[i].validate())
+	}
+	return errs
+}
+
+// Validate checks the semantics of the stakeholders.
+func (s *Stakeholders) Validate() error {
+	return s.validate().err()
+}
+
+// fieldErrors lists the properties which cannot be decoded along the
+// semantic errors, the semantic errors of these properties are dropped, e.g.
+// a required timestamp which is not RFC 3339.
+func fieldErrors(decoded decodeErrors, semantic ValidationErrors) ValidationErrors {
+	errs := ValidationErrors{}
+	for _, err := range decoded {
+		var pathErr *PathError
+		if !errors.As(err, &pathErr) {
+			errs.add("", "%s", err)
+			continue
+		}
+
+		msg := pathErr.Err.Error()
+		if len(pathErr.Detail) > 0 {
+			msg += ": " + pathErr.Detail
+		}
+		errs.add(pathErr.Path, "%s", msg)
+		semantic = semantic.without(pathErr.Path)
+	}
+	return append(errs, semantic...)
+}
+
+// Validate checks the semantics of an ISCN block. The parent of a content
+// block is fetched with the getter to check the version, the check is skipped
+// if the getter is nil. The returned error is a ValidationErrors listing the
+// properties which cannot be decoded and the invalid ones.
+func Validate(
+	ctx context.Context,
+	getter ipld.NodeGetter,
+	obj iscn.IscnObject,
+) error {
+	switch obj.Cid().Type() {
+	case iscn.CodecISCN:
+		k := Kernel{}
+		decoded := k.fromObject(obj)
+		return fieldErrors(decoded, k.validate()).err()
+	case iscn.CodecEntity:
+		e := Entity{}
+		decoded := e.fromObject(obj)
+		return fieldErrors(decoded, e.validate()).err()
+	case iscn.CodecRights:
+		r := Rights{}
+		decoded := r.fromObject(obj)
+		return fieldErrors(decoded, r.validate()).err()
+	case iscn.CodecStakeholders:
+		s := Stakeholders{}
+		decoded := s.fromObject(obj)
+		return fieldErrors(decoded, s.validate()).err()
+	case iscn.CodecContent:
+		c := Content{}
+		decoded := c.fromObject(obj)
+
+		if getter == nil || !c.Parent.Defined() {
+			return fieldErrors(decoded, c.validate(nil)).err()
+		}
+
+		nd, err := getter.Get(ctx, c.Parent)
+		if err != nil {
+			return fmt.Errorf("cannot fetch parent %s: %s", c.Parent, err)
+		}
+		parentObj, err := iscn.Decode(nd.RawData(), c.Parent)
+		if err != nil {
+			return fmt.Errorf("cannot decode parent %s: %s", c.Parent, err)
+		}
+		parent := Content{}
+		if err := parent.FromObject(parentObj); err != nil {
+			return fmt.Errorf("parent %s: %s", c.Parent, err)
+		}
+		return fieldErrors(decoded, c.validate(&parent)).err()
+	default:
+		return &PathError{
+			Err:    ErrInvalidCodec,
+			Detail: fmt.Sprintf("block %s is not an ISCN block", obj.Cid()),
+		}
+	}
+}
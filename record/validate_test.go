@@ -0,0 +1,48 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	iscn "github.com/likecoin/iscn-ipld/plugin/block"
+)
+
+func TestValidateListsDecodeErrors(t *testing.T) {
+	entity := demoBlocks(t)[4]
+
+	rights, err := iscn.Encode(iscn.CodecRights, SchemaVersion, map[string]interface{}{
+		"rights": []map[string]interface{}{
+			{
+				"holder": entity.Cid(),
+				"type":   "",
+				"terms":  entity.Cid(),
+				"period": map[string]interface{}{
+					"from": "yesterday",
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("cannot encode rights: %s", err)
+	}
+
+	err = Validate(context.Background(), nil, rights)
+	errs := ValidationErrors{}
+	if !errors.As(err, &errs) {
+		t.Fatalf("Validate() = %v, want ValidationErrors", err)
+	}
+
+	paths := map[string]bool{}
+	for _, fieldErr := range errs {
+		paths[fieldErr.Path] = true
+	}
+	for _, path := range []string{"rights/0/period/from", "rights/0/type"} {
+		if !paths[path] {
+			t.Errorf("Validate() = %s, want an error of %s", err, path)
+		}
+	}
+	if len(errs) != 2 {
+		t.Errorf("Validate() = %s, want 2 errors", err)
+	}
+}